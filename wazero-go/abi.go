@@ -0,0 +1,40 @@
+package reactor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/tetratelabs/wazero/api"
+)
+
+// SupportedABIVersion is the version of the reactor ABI (go_start_main,
+// go_tick, and their semantics) implemented by this package.
+const SupportedABIVersion = 1
+
+// ErrABIMismatch is returned by NewReactor and Instantiate when the guest
+// reports a reactor ABI version other than SupportedABIVersion.
+var ErrABIMismatch = errors.New("reactor ABI version mismatch")
+
+// checkABIVersion compares the guest's optional go_abi_version export, an i32
+// global or a function returning i32, with SupportedABIVersion. Guests
+// without it are assumed to use the legacy ABI, version 1.
+func (r *Reactor) checkABIVersion(ctx context.Context) error {
+	var version int32
+	if g := r.mod.ExportedGlobal("go_abi_version"); g != nil {
+		version = api.DecodeI32(g.Get())
+	} else if fn := r.mod.ExportedFunction("go_abi_version"); fn != nil {
+		results, err := r.call(ctx, fn)
+		if err != nil {
+			return fmt.Errorf("go_abi_version: %w", err)
+		}
+		version = api.DecodeI32(results[0])
+	} else {
+		r.logger().Debug("guest does not export go_abi_version; assuming legacy ABI", "reactor", r.cfg.Name)
+		return nil
+	}
+	if version != SupportedABIVersion {
+		return fmt.Errorf("%w: guest version %d, host supports %d", ErrABIMismatch, version, SupportedABIVersion)
+	}
+	return nil
+}
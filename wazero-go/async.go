@@ -0,0 +1,69 @@
+package reactor
+
+import (
+	"context"
+	"sync"
+
+	"github.com/tetratelabs/wazero"
+)
+
+// PendingReactor is a reactor being created in the background by
+// NewReactorAsync.
+type PendingReactor struct {
+	once    sync.Once
+	done    chan struct{}
+	reactor *Reactor
+	err     error
+}
+
+// NewReactorAsync is like NewReactor, but compiles and instantiates in a
+// background goroutine so the caller stays responsive while a large module
+// compiles. The result is available from the returned PendingReactor.
+//
+// wazero neither reports compile progress nor stops a compile part-way, so
+// progress can only be observed as completion via Done. Cancelling ctx
+// settles the PendingReactor with the context's error straight away; the
+// compile finishes in the background, but its module is then discarded
+// without being instantiated, so _initialize does not run.
+func NewReactorAsync(ctx context.Context, r wazero.Runtime, wasm []byte, cfg *Config) *PendingReactor {
+	p := &PendingReactor{done: make(chan struct{})}
+	stop := context.AfterFunc(ctx, func() {
+		p.settle(nil, context.Cause(ctx))
+	})
+	go func() {
+		defer stop()
+		reactor, err := NewReactor(ctx, r, wasm, cfg)
+		if !p.settle(reactor, err) && reactor != nil {
+			_ = reactor.Close(context.WithoutCancel(ctx))
+		}
+	}()
+	return p
+}
+
+// settle records the result unless one was already recorded, reporting
+// whether it did.
+func (p *PendingReactor) settle(reactor *Reactor, err error) bool {
+	settled := false
+	p.once.Do(func() {
+		p.reactor, p.err = reactor, err
+		settled = true
+		close(p.done)
+	})
+	return settled
+}
+
+// Done is closed once the reactor has been created or has failed.
+func (p *PendingReactor) Done() <-chan struct{} {
+	return p.done
+}
+
+// Wait waits for the reactor to be created and returns it. If ctx is done
+// first, Wait returns ctx's error and the reactor remains pending.
+func (p *PendingReactor) Wait(ctx context.Context) (*Reactor, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-p.done:
+		return p.reactor, p.err
+	}
+}
@@ -0,0 +1,67 @@
+package reactor
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+)
+
+func TestNewReactorAsyncCancelMidCompile(t *testing.T) {
+	// Enough code that compiling takes a while, with _initialize reporting
+	// whether it ran
+	m := reactorModule(i32Const(int32(LoopIdle)))
+	m.imports = []testImport{{module: "test", name: "init"}}
+	m.funcs[0].body = callFunc(0)
+	var filler []byte
+	for i := range 2000 {
+		filler = slices.Concat(filler, i32Const(int32(i)), i32Const(3), []byte{0x6c, 0x1a}) // drop(i * 3)
+	}
+	for range 200 {
+		m.funcs = append(m.funcs, testFunc{body: filler})
+	}
+	wasm := m.encode()
+
+	ctx := context.Background()
+	rt := wazero.NewRuntime(ctx)
+	defer rt.Close(ctx)
+	start := time.Now()
+	if _, err := rt.CompileModule(ctx, wasm); err != nil {
+		t.Fatal(err)
+	}
+	compileTime := time.Since(start)
+	if compileTime < 20*time.Millisecond {
+		t.Skipf("compile took only %v, too quick to cancel part-way", compileTime)
+	}
+
+	var inits atomic.Int32
+	cfg := &Config{HostModules: []HostModule{{
+		Name: "test",
+		Functions: []HostFunction{{
+			Name: "init",
+			Func: func(context.Context, api.Module, []uint64) { inits.Add(1) },
+		}},
+	}}}
+	rt2 := wazero.NewRuntime(ctx)
+	defer rt2.Close(ctx)
+	compileCtx, cancel := context.WithCancel(ctx)
+	p := NewReactorAsync(compileCtx, rt2, wasm, cfg)
+	time.Sleep(compileTime / 4)
+	cancel()
+
+	waitCtx, waitCancel := context.WithTimeout(ctx, compileTime/2)
+	defer waitCancel()
+	if _, err := p.Wait(waitCtx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, want context.Canceled", err)
+	}
+	// Give the abandoned compile time to finish
+	time.Sleep(3 * compileTime)
+	if n := inits.Load(); n != 0 {
+		t.Errorf("_initialize ran %d times after the compile was cancelled", n)
+	}
+}
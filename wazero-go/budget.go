@@ -0,0 +1,110 @@
+package reactor
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// ErrBudgetExceeded is returned by LoopOnce when the reactor's Budget is used
+// up. The guest is not ticked.
+var ErrBudgetExceeded = errors.New("reactor budget exceeded")
+
+// BudgetLimits are the aggregate limits of a Budget. Zero fields are
+// unlimited.
+type BudgetLimits struct {
+	// MemoryPages limits the total linear memory, in 64KiB pages, of the
+	// budget's live reactors.
+	MemoryPages uint64
+	// Ticks limits the total number of go_tick calls.
+	Ticks uint64
+	// TickTime limits the total wall time spent inside go_tick.
+	TickTime time.Duration
+}
+
+// BudgetUsage is the resource usage charged to a Budget, in total or by a
+// single reactor.
+type BudgetUsage struct {
+	// MemoryPages is the current linear memory size in 64KiB pages. Memory
+	// is released from the budget when a reactor is closed.
+	MemoryPages uint64
+	// Ticks is the number of go_tick calls so far.
+	Ticks uint64
+	// TickTime is the wall time spent inside go_tick so far.
+	TickTime time.Duration
+}
+
+// Budget enforces limits across a group of reactors, e.g. all reactors of
+// one tenant, rather than per reactor. Reactors join via Config.Budget. Once
+// any limit is exceeded, LoopOnce on every member returns ErrBudgetExceeded
+// without ticking the guest; ticks already running finish, so usage may end up
+// slightly over the limit.
+//
+// Usage is tracked with atomic counters, so members can tick concurrently
+// without contending on a lock.
+type Budget struct {
+	limits   BudgetLimits
+	pages    atomic.Uint64
+	ticks    atomic.Uint64
+	tickTime atomic.Int64
+}
+
+// NewBudget constructs a Budget with the given limits.
+func NewBudget(limits BudgetLimits) *Budget {
+	return &Budget{limits: limits}
+}
+
+// Limits returns the budget's limits.
+func (b *Budget) Limits() BudgetLimits {
+	return b.limits
+}
+
+// Usage returns the usage charged to the budget by all its reactors.
+func (b *Budget) Usage() BudgetUsage {
+	return BudgetUsage{
+		MemoryPages: b.pages.Load(),
+		Ticks:       b.ticks.Load(),
+		TickTime:    time.Duration(b.tickTime.Load()),
+	}
+}
+
+// Exceeded reports whether any limit has been exceeded.
+func (b *Budget) Exceeded() bool {
+	l := b.limits
+	return (l.MemoryPages > 0 && b.pages.Load() > l.MemoryPages) ||
+		(l.Ticks > 0 && b.ticks.Load() >= l.Ticks) ||
+		(l.TickTime > 0 && time.Duration(b.tickTime.Load()) >= l.TickTime)
+}
+
+// charge records one tick that took d.
+func (b *Budget) charge(d time.Duration) {
+	b.ticks.Add(1)
+	b.tickTime.Add(int64(d))
+}
+
+// BudgetUsage returns this reactor's contribution to its Budget. Memory is
+// counted while the reactor is live, and ticks since it was created.
+func (r *Reactor) BudgetUsage() BudgetUsage {
+	u := BudgetUsage{
+		Ticks:    r.ticks.Load(),
+		TickTime: time.Duration(r.tickTime.Load()),
+	}
+	if !r.closed.Load() {
+		u.MemoryPages = uint64(r.memPages.Load())
+	}
+	return u
+}
+
+// joinBudget charges the reactor's current memory to its budget.
+func (r *Reactor) joinBudget() {
+	if b := r.cfg.Budget; b != nil && r.budgeted.CompareAndSwap(false, true) {
+		b.pages.Add(uint64(r.memPages.Load()))
+	}
+}
+
+// leaveBudget releases the reactor's memory from its budget.
+func (r *Reactor) leaveBudget() {
+	if b := r.cfg.Budget; b != nil && r.budgeted.CompareAndSwap(true, false) {
+		b.pages.Add(-uint64(r.memPages.Load()))
+	}
+}
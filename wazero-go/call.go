@@ -0,0 +1,169 @@
+package reactor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/tetratelabs/wazero/api"
+)
+
+// ErrNoResult is returned by CallAndReadResult when the guest goes idle
+// without publishing a result.
+var ErrNoResult = errors.New("guest went idle without producing a result")
+
+// Call invokes an exported guest function with the given parameters.
+//
+// Call does not tick the scheduler: any goroutines the export queues run on
+// subsequent LoopOnce or Run calls.
+func (r *Reactor) Call(ctx context.Context, name string, params ...uint64) ([]uint64, error) {
+	fn := r.mod.ExportedFunction(name)
+	if fn == nil {
+		return nil, fmt.Errorf("module does not export %s", name)
+	}
+	return r.call(ctx, fn, params...)
+}
+
+// CallAndReadResult writes input into guest memory, invokes the named export,
+// ticks until the guest publishes a result, and returns a copy of it.
+//
+// The guest must follow this ABI:
+//
+//	go_alloc(size i32) i32            // returns a buffer of at least size bytes
+//	<name>(ptr i32, len i32)          // handles the request in [ptr, ptr+len)
+//	go_last_result_len() i32          // -1 while pending, else the result length
+//	go_last_result_ptr() i32          // address of the result bytes
+//
+// The export may publish the result synchronously or from a goroutine. The
+// buffer from go_alloc and the result region must remain valid until the next
+// call. If input is empty, go_alloc is not called and ptr is 0.
+//
+// Returns ErrNoResult if the guest goes idle while the result is pending.
+func (r *Reactor) CallAndReadResult(ctx context.Context, name string, input []byte) ([]byte, error) {
+	resultLen, err := r.optionalExport("go_last_result_len")
+	if err != nil {
+		return nil, err
+	}
+	resultPtr, err := r.optionalExport("go_last_result_ptr")
+	if err != nil {
+		return nil, err
+	}
+
+	var ptr uint32
+	if len(input) != 0 {
+		alloc, err := r.optionalExport("go_alloc")
+		if err != nil {
+			return nil, err
+		}
+		res, err := r.call(ctx, alloc, api.EncodeI32(int32(len(input))))
+		if err != nil {
+			return nil, fmt.Errorf("go_alloc: %w", err)
+		}
+		ptr = api.DecodeU32(res[0])
+		if !r.mod.Memory().Write(ptr, input) {
+			return nil, fmt.Errorf("go_alloc returned out of range buffer %d+%d", ptr, len(input))
+		}
+	}
+
+	if _, err := r.Call(ctx, name, api.EncodeU32(ptr), api.EncodeI32(int32(len(input)))); err != nil {
+		return nil, err
+	}
+
+	var n int32
+	err = r.tickUntil(ctx, func() (bool, error) {
+		res, err := r.call(ctx, resultLen)
+		if err != nil {
+			return false, fmt.Errorf("go_last_result_len: %w", err)
+		}
+		n = api.DecodeI32(res[0])
+		return n >= 0, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := r.call(ctx, resultPtr)
+	if err != nil {
+		return nil, fmt.Errorf("go_last_result_ptr: %w", err)
+	}
+	out, ok := r.mod.Memory().Read(api.DecodeU32(res[0]), uint32(n))
+	if !ok {
+		return nil, fmt.Errorf("result region out of range")
+	}
+	return append([]byte(nil), out...), nil
+}
+
+// RunUntilExport ticks the guest until predicate accepts the value of the
+// named export, for guests that signal completion, e.g. a response being
+// ready, through a flag rather than a function result. The export may be a
+// global, whose value is read, or a function taking no parameters and
+// returning one value, which is called. The raw value is passed to predicate,
+// so use api.DecodeI32 and friends to interpret it.
+//
+// The export is checked before the first tick and after every tick. Like
+// CallAndReadResult, it waits on guest timers and returns ErrNoResult if the
+// guest goes idle first. If the guest exits, the exit error is returned. If
+// ctx expires, its error, e.g. context.DeadlineExceeded, is returned.
+func (r *Reactor) RunUntilExport(ctx context.Context, name string, predicate func(uint64) bool) error {
+	var read func() (uint64, error)
+	if g := r.mod.ExportedGlobal(name); g != nil {
+		read = func() (uint64, error) { return g.Get(), nil }
+	} else if fn := r.mod.ExportedFunction(name); fn != nil {
+		read = func() (uint64, error) {
+			res, err := r.call(ctx, fn)
+			if err != nil {
+				return 0, fmt.Errorf("%s: %w", name, err)
+			}
+			if len(res) != 1 {
+				return 0, fmt.Errorf("%s returned %d results, want 1", name, len(res))
+			}
+			return res[0], nil
+		}
+	} else {
+		return fmt.Errorf("module does not export %s", name)
+	}
+
+	return r.tickUntil(ctx, func() (bool, error) {
+		v, err := read()
+		if err != nil {
+			return false, err
+		}
+		return predicate(v), nil
+	})
+}
+
+// tickUntil drives the scheduler until done reports true, waiting on guest
+// timers as Run does. Returns ErrNoResult if the guest goes idle first.
+func (r *Reactor) tickUntil(ctx context.Context, done func() (bool, error)) error {
+	for {
+		ok, err := done()
+		if err != nil || ok {
+			return err
+		}
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		result, err := r.LoopOnce(ctx)
+		if err != nil {
+			return fmt.Errorf("loop once: %w", err)
+		}
+
+		switch {
+		case result == LoopIdle:
+			if ok, err := done(); err != nil || ok {
+				return err
+			}
+			return ErrNoResult
+		case result == LoopWaitForever:
+			if err := r.waitNotify(ctx); err != nil {
+				return err
+			}
+		case result > 0:
+			if err := r.waitTimer(ctx, result); err != nil {
+				return err
+			}
+		}
+	}
+}
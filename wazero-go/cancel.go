@@ -0,0 +1,79 @@
+package reactor
+
+import (
+	"context"
+	"fmt"
+)
+
+// CancelReason tells the guest why the host cancelled a run.
+type CancelReason int32
+
+const (
+	// CancelShutdown indicates the host is shutting down.
+	CancelShutdown CancelReason = 1
+	// CancelTimeout indicates the guest ran out of time.
+	CancelTimeout CancelReason = 2
+	// CancelError indicates the host hit an error and is abandoning the run.
+	CancelError CancelReason = 3
+)
+
+// CanceledError is returned by Run, RunWithCallback, and Serve when the run was
+// cancelled via Cancel. It matches context.Canceled with errors.Is.
+type CanceledError struct {
+	Reason CancelReason
+}
+
+// Error implements error.
+func (e *CanceledError) Error() string {
+	return fmt.Sprintf("reactor run canceled (reason %d)", e.Reason)
+}
+
+// Is reports whether target is context.Canceled.
+func (e *CanceledError) Is(target error) bool {
+	return target == context.Canceled
+}
+
+// Cancel cancels the run in progress, if any, with the given reason.
+//
+// When the run stops, the reason is passed to the guest's optional
+// go_set_cancel_reason(code i32) export, and if Config.FinalTickOnCancel is
+// set the guest gets one more tick to observe it, e.g. to flush state. Guests
+// that do not export go_set_cancel_reason see a plain cancellation.
+// It is safe to call from any goroutine.
+func (r *Reactor) Cancel(reason CancelReason) {
+	r.runMu.Lock()
+	cancel := r.runCancel
+	r.runMu.Unlock()
+	if cancel != nil {
+		cancel(&CanceledError{Reason: reason})
+	}
+}
+
+// setRunCancel records the cancel func of the run in progress.
+func (r *Reactor) setRunCancel(cancel context.CancelCauseFunc) {
+	r.runMu.Lock()
+	r.runCancel = cancel
+	r.runMu.Unlock()
+}
+
+// finishCancel delivers a cancel reason to the guest after a run was
+// cancelled. ctx is already done, so the guest is called without its
+// cancellation.
+func (r *Reactor) finishCancel(ctx context.Context, reason CancelReason) {
+	if r.closed.Load() {
+		return
+	}
+	ctx = context.WithoutCancel(ctx)
+
+	if fn := r.mod.ExportedFunction("go_set_cancel_reason"); fn != nil {
+		if _, err := r.call(ctx, fn, uint64(uint32(reason))); err != nil {
+			r.logger().Warn("go_set_cancel_reason failed", "reactor", r.cfg.Name, "error", err)
+			return
+		}
+	}
+	if r.cfg.FinalTickOnCancel {
+		if _, err := r.LoopOnce(ctx); err != nil {
+			r.logger().Warn("final tick after cancel failed", "reactor", r.cfg.Name, "error", err)
+		}
+	}
+}
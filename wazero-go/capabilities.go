@@ -0,0 +1,57 @@
+package reactor
+
+// Capabilities selects which WASI capabilities a guest may use, for
+// sandboxing untrusted reactors. See Config.Capabilities.
+//
+// Denied capabilities are stubbed rather than removed, so the guest links and
+// runs normally, but observes the values described per field.
+type Capabilities struct {
+	// AllowFS mounts Config.FS and Config.ProfileDir. When false, no file
+	// system is mounted: the guest has no pre-opened directories, and opening
+	// any path fails with ENOENT or EBADF.
+	AllowFS bool
+	// AllowClock applies Config.Clock and Config.ClockResolution. When false,
+	// both are ignored and the guest gets wazero's deterministic fake clocks,
+	// which start at a fixed time and advance 1ms per reading regardless of
+	// real time. Those are also what the guest gets with neither setting, so
+	// denying the clock only changes anything if one of them is set.
+	AllowClock bool
+	// AllowRandom lets random_get read from wazero's default source. When
+	// false, random_get fills every buffer with zeros. Config.ReplayRandom
+	// overrides both. wazero's default is itself a pseudo-random generator with a
+	// fixed seed, so neither gives a guest unpredictable bytes: anything it
+	// seeds from random_get, such as crypto/rand output or map iteration
+	// order, is reproducible either way.
+	AllowRandom bool
+	// AllowEnv passes Config.Env. When false, the environment is empty.
+	AllowEnv bool
+	// AllowArgs passes Config.Args. When false, the guest sees only the
+	// program name: Args[0], or "reactor" if Args is empty.
+	AllowArgs bool
+}
+
+// AllCapabilities allows every capability; it is the behavior when
+// Config.Capabilities is nil.
+var AllCapabilities = Capabilities{
+	AllowFS:     true,
+	AllowClock:  true,
+	AllowRandom: true,
+	AllowEnv:    true,
+	AllowArgs:   true,
+}
+
+// capabilities returns the effective capabilities.
+func (cfg *Config) capabilities() Capabilities {
+	if cfg.Capabilities == nil {
+		return AllCapabilities
+	}
+	return *cfg.Capabilities
+}
+
+// zeroReader is an io.Reader of infinite zeros.
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
@@ -0,0 +1,80 @@
+package reactor
+
+import (
+	"io"
+	"sync"
+)
+
+// captureBuffer keeps the most recent output written to a guest stream.
+type captureBuffer struct {
+	mu    sync.Mutex
+	buf   []byte
+	limit int
+}
+
+// Write implements io.Writer. It never fails; when over the limit the oldest
+// bytes are dropped.
+func (c *captureBuffer) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.buf = append(c.buf, p...)
+	if c.limit > 0 && len(c.buf) > c.limit {
+		c.buf = append(c.buf[:0], c.buf[len(c.buf)-c.limit:]...)
+	}
+	return len(p), nil
+}
+
+// drain returns the captured bytes and clears the buffer.
+func (c *captureBuffer) drain() []byte {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := c.buf
+	c.buf = nil
+	return out
+}
+
+// captureWriter copies writes to a captureBuffer before passing them on.
+type captureWriter struct {
+	w   io.Writer
+	buf *captureBuffer
+	// norm, if set, normalizes line endings on the way into buf.
+	norm *lineNormalizer
+}
+
+// Write implements io.Writer.
+func (c *captureWriter) Write(p []byte) (int, error) {
+	if c.norm != nil {
+		_, _ = c.norm.Write(p)
+	} else {
+		_, _ = c.buf.Write(p)
+	}
+	return c.w.Write(p)
+}
+
+// Flush implements Flusher, writing a CR held back by norm to buf and
+// flushing the underlying writer if it is a Flusher.
+func (c *captureWriter) Flush() error {
+	if c.norm != nil {
+		_ = c.norm.Flush()
+	}
+	if f, ok := c.w.(Flusher); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+// DrainStdout returns the stdout captured since the last drain and clears it,
+// so a long-running host can ship output incrementally. It returns nil unless
+// Config.CaptureOutput is set. It is safe to call concurrently with ticks:
+// each write from the guest is either wholly in this drain or in the next.
+func (r *Reactor) DrainStdout() []byte {
+	return r.stdoutCapture.drain()
+}
+
+// DrainStderr is like DrainStdout for stderr.
+func (r *Reactor) DrainStderr() []byte {
+	return r.stderrCapture.drain()
+}
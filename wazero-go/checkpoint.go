@@ -0,0 +1,97 @@
+package reactor
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/tetratelabs/wazero"
+)
+
+// ErrCheckpointMismatch is returned by OpenCheckpoint when the checkpoint was
+// saved from a different wasm module.
+var ErrCheckpointMismatch = errors.New("checkpoint does not match module")
+
+// checkpointMagic identifies a checkpoint file, followed by a format version,
+// the SHA-256 of the wasm, and a snapshot as taken by Reactor.Snapshot.
+const (
+	checkpointMagic   = "GRCK"
+	checkpointVersion = 1
+)
+
+// SaveCheckpoint writes the reactor's state to a file at path, from which
+// OpenCheckpoint can resume it, e.g. after the host process restarts. The
+// file is written to a temporary file first and renamed into place, so an
+// existing checkpoint is only replaced once the new one is complete. It must
+// not be called while a tick is in progress.
+//
+// A checkpoint holds the guest's linear memory and globals, whether main was
+// started, and the hash of the wasm. Host-side state is not captured: open
+// files and their offsets, the contents of Stdin and output already written,
+// Config settings, host module state, timers pending in the host, and
+// counters such as Ticks all start afresh on resume. See Reactor.Snapshot for
+// the consistency implications.
+func (r *Reactor) SaveCheckpoint(path string) error {
+	snapshot, err := r.Snapshot()
+	if err != nil {
+		return err
+	}
+	buf := make([]byte, 0, len(checkpointMagic)+4+sha256.Size+len(snapshot))
+	buf = append(buf, checkpointMagic...)
+	buf = binary.LittleEndian.AppendUint32(buf, checkpointVersion)
+	buf = append(buf, r.compiled.hash[:]...)
+	buf = append(buf, snapshot...)
+
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(buf); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return nil
+}
+
+// OpenCheckpoint compiles wasm and instantiates it from the checkpoint at
+// path, written by SaveCheckpoint, instead of calling _initialize. cfg is
+// applied as by NewReactor; its Store, if any, is not loaded from.
+// Returns ErrCheckpointMismatch if the checkpoint was taken from different
+// wasm, and ErrBadSnapshot if it is corrupt.
+func OpenCheckpoint(ctx context.Context, r wazero.Runtime, wasm []byte, path string, cfg *Config) (*Reactor, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	hdr := len(checkpointMagic) + 4 + sha256.Size
+	if len(data) < hdr || string(data[:len(checkpointMagic)]) != checkpointMagic {
+		return nil, fmt.Errorf("%w: bad checkpoint header", ErrBadSnapshot)
+	}
+	if v := binary.LittleEndian.Uint32(data[len(checkpointMagic):]); v != checkpointVersion {
+		return nil, fmt.Errorf("%w: unsupported checkpoint version %d", ErrBadSnapshot, v)
+	}
+	hash := sha256.Sum256(wasm)
+	if !bytes.Equal(data[len(checkpointMagic)+4:hdr], hash[:]) {
+		return nil, ErrCheckpointMismatch
+	}
+
+	compiled, err := Compile(ctx, r, wasm)
+	if err != nil {
+		return nil, err
+	}
+	return compiled.instantiateReactor(ctx, cfg, data[hdr:])
+}
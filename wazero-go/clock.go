@@ -0,0 +1,59 @@
+package reactor
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Clock supplies the guest's view of time. See Config.Clock.
+type Clock interface {
+	// Walltime returns the current unix time in seconds and nanoseconds.
+	Walltime() (sec int64, nsec int32)
+	// Nanotime returns a monotonic reading in nanoseconds.
+	Nanotime() int64
+}
+
+// advancer is implemented by clocks that the run loop advances instead of
+// waiting on guest timers, such as FastForwardClock.
+type advancer interface {
+	Advance(d time.Duration)
+}
+
+// FastForwardClock is a Clock that only moves when advanced. When it is the
+// Config.Clock, the run loop advances it by each pending guest timer instead
+// of waiting, so a guest that sleeps for hours finishes as fast as it can
+// compute. Timers still fire in the same order as in real time.
+//
+// Combined with Config.SchedulerSeed this makes time-dependent guests fully
+// reproducible, which is useful for tests.
+type FastForwardClock struct {
+	start   time.Time
+	elapsed atomic.Int64
+}
+
+// NewFastForwardClock returns a FastForwardClock whose wall time starts at
+// start.
+func NewFastForwardClock(start time.Time) *FastForwardClock {
+	return &FastForwardClock{start: start}
+}
+
+// Walltime implements Clock.
+func (c *FastForwardClock) Walltime() (sec int64, nsec int32) {
+	t := c.start.Add(time.Duration(c.elapsed.Load()))
+	return t.Unix(), int32(t.Nanosecond())
+}
+
+// Nanotime implements Clock.
+func (c *FastForwardClock) Nanotime() int64 {
+	return c.elapsed.Load()
+}
+
+// Advance moves the clock forward by d.
+func (c *FastForwardClock) Advance(d time.Duration) {
+	c.elapsed.Add(int64(d))
+}
+
+// Elapsed returns the total time the clock has been advanced.
+func (c *FastForwardClock) Elapsed() time.Duration {
+	return time.Duration(c.elapsed.Load())
+}
@@ -0,0 +1,22 @@
+package reactor
+
+import "context"
+
+// Clone instantiates a fresh reactor from the same compiled module as r,
+// running _initialize anew, for handing each request an isolated instance
+// without tracking the CompiledReactor separately. cfg configures the new
+// instance; if nil, r's configuration is reused, including its stdio but not
+// its Store, so the clone neither loads nor overwrites r's snapshots.
+//
+// Clone does not copy r's runtime state: the clone starts from scratch, with
+// its own memory, as if freshly instantiated. To carry state over, use
+// Snapshot with Config.Store, or SaveCheckpoint and OpenCheckpoint, instead.
+// r is unaffected and may be closed independently.
+func (r *Reactor) Clone(ctx context.Context, cfg *Config) (*Reactor, error) {
+	if cfg == nil {
+		c := r.cfg
+		c.Store = nil
+		cfg = &c
+	}
+	return r.compiled.Instantiate(ctx, cfg)
+}
@@ -0,0 +1,37 @@
+package reactor
+
+import (
+	"context"
+	"testing"
+)
+
+func TestClone(t *testing.T) {
+	m := reactorModule(countTick(i32Const(int32(LoopReady)), LoopIdle))
+	m.globals = 1
+	r := newTestReactor(t, m, nil)
+	ctx := context.Background()
+	if err := r.Run(ctx); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+
+	c, err := r.Clone(ctx, nil)
+	if err != nil {
+		t.Fatalf("clone: %v", err)
+	}
+	defer c.Close(ctx)
+	if n := global(c, 0); n != 0 {
+		t.Errorf("clone starts at tick count %d, want 0", n)
+	}
+	if err := c.Run(ctx); err != nil {
+		t.Fatalf("run clone: %v", err)
+	}
+	if n := global(c, 0); n != 2 {
+		t.Errorf("clone ticked %d times, want 2", n)
+	}
+	if n := global(r, 0); n != 2 {
+		t.Errorf("original ticked %d times after the clone ran, want 2", n)
+	}
+	if c.Ticks() != 2 || r.Ticks() != 2 {
+		t.Errorf("Ticks = %d for the original and %d for the clone, want 2 each", r.Ticks(), c.Ticks())
+	}
+}
@@ -0,0 +1,129 @@
+package reactor
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Cold start phases reported by ColdStartError.
+const (
+	// PhaseInstantiate covers instantiating host modules and the guest.
+	PhaseInstantiate = "instantiate"
+	// PhaseInitialize covers _initialize and the checks that follow it.
+	PhaseInitialize = "initialize"
+	// PhaseStartMain covers go_start_main.
+	PhaseStartMain = "start_main"
+	// PhaseReady covers ticking until the guest stops reporting LoopReady.
+	PhaseReady = "ready"
+)
+
+// ColdStartError is returned by NewAndWaitReady, reporting which phase of the
+// cold start failed or ran out of time, and how long each phase took.
+type ColdStartError struct {
+	// Phase is the phase that failed, e.g. PhaseReady.
+	Phase string
+	// Timings are the durations of the phases, up to and including the one
+	// that failed. Phases not reached are zero.
+	Timings ColdStartTimings
+	// Err is the underlying error, e.g. context.DeadlineExceeded.
+	Err error
+}
+
+// ColdStartTimings are the durations of each phase of a cold start.
+type ColdStartTimings struct {
+	Instantiate time.Duration
+	Initialize  time.Duration
+	StartMain   time.Duration
+	Ready       time.Duration
+}
+
+// Error implements error.
+func (e *ColdStartError) Error() string {
+	t := e.Timings
+	return fmt.Sprintf("cold start failed in %s phase: %v (instantiate %v, initialize %v, start_main %v, ready %v)",
+		e.Phase, e.Err, t.Instantiate, t.Initialize, t.StartMain, t.Ready)
+}
+
+// Unwrap returns the underlying error.
+func (e *ColdStartError) Unwrap() error {
+	return e.Err
+}
+
+// NewAndWaitReady instantiates compiled, calls _initialize and StartMain, and
+// ticks until the guest stops reporting LoopReady, i.e. it has gone idle or is
+// waiting on a timer or the host, all under ctx, so one deadline covers the
+// whole cold start. It returns the warmed reactor, for the caller to keep
+// driving with Run or LoopOnce.
+//
+// On failure the reactor is closed and a *ColdStartError is returned, naming
+// the phase that failed or exceeded the deadline along with the time each
+// phase took. If cfg.Timings is set it is filled in as by Instantiate.
+func NewAndWaitReady(ctx context.Context, compiled *CompiledReactor, cfg *Config) (*Reactor, error) {
+	var c Config
+	if cfg != nil {
+		c = *cfg
+	}
+	var timings Timings
+	c.Timings = &timings
+	c.AutoStart = false
+
+	fail := func(r *Reactor, phase string, t ColdStartTimings, err error) (*Reactor, error) {
+		if r != nil {
+			_ = r.Close(ctx)
+		}
+		return nil, &ColdStartError{Phase: phase, Timings: t, Err: err}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return fail(nil, PhaseInstantiate, ColdStartTimings{}, err)
+	}
+	start := time.Now()
+	r, err := compiled.Instantiate(ctx, &c)
+	if cfg != nil && cfg.Timings != nil {
+		*cfg.Timings = timings
+	}
+	t := ColdStartTimings{Instantiate: timings.Instantiate}
+	if err != nil {
+		if timings.Instantiate == 0 {
+			t.Instantiate = time.Since(start)
+			return fail(nil, PhaseInstantiate, t, err)
+		}
+		t.Initialize = time.Since(start) - timings.Instantiate
+		return fail(nil, PhaseInitialize, t, err)
+	}
+	t.Initialize = time.Since(start) - timings.Instantiate
+	r.cfg.Timings = nil
+	if cfg != nil {
+		r.cfg.Timings = cfg.Timings
+	}
+	if err := ctx.Err(); err != nil {
+		return fail(r, PhaseInitialize, t, err)
+	}
+
+	start = time.Now()
+	err = r.StartMain(ctx)
+	t.StartMain = time.Since(start)
+	if err == nil {
+		err = ctx.Err()
+	}
+	if err != nil {
+		return fail(r, PhaseStartMain, t, err)
+	}
+
+	start = time.Now()
+	for {
+		if err := ctx.Err(); err != nil {
+			t.Ready = time.Since(start)
+			return fail(r, PhaseReady, t, err)
+		}
+		result, err := r.LoopOnce(ctx)
+		if err != nil {
+			t.Ready = time.Since(start)
+			return fail(r, PhaseReady, t, err)
+		}
+		if result != LoopReady {
+			return r, nil
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package reactor
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+// Runner is a module that can be run to completion: a *Reactor for WASI
+// reactors, or a *CommandReactor for classic WASI commands.
+type Runner interface {
+	// Run executes the module until it finishes.
+	Run(ctx context.Context) error
+	// Close releases resources associated with the module.
+	Close(ctx context.Context) error
+}
+
+// CommandReactor runs a classic WASI command module, which exports _start
+// instead of the reactor ABI, as a one-shot program.
+//
+// Unlike a Reactor, the host cannot interleave work with the guest: Run blocks
+// in _start until the program exits, and timers are serviced by the guest
+// itself.
+type CommandReactor struct {
+	mod      api.Module
+	start    api.Function
+	ran      atomic.Bool
+	exitCode atomic.Uint32
+}
+
+// Open compiles wasm and instantiates it as a Reactor if it exports the
+// reactor ABI (_initialize, go_start_main, go_tick). Otherwise, if it exports
+// _start, it falls back to a CommandReactor, so one harness can run both
+// kinds of module. See CompiledReactor.InstantiateRunner.
+func Open(ctx context.Context, r wazero.Runtime, wasm []byte, cfg *Config) (Runner, error) {
+	compiled, err := Compile(ctx, r, wasm)
+	if err != nil {
+		return nil, err
+	}
+	return compiled.InstantiateRunner(ctx, cfg)
+}
+
+// IsCommand reports whether the module lacks the reactor ABI but exports
+// _start, and so runs as a CommandReactor.
+func (c *CompiledReactor) IsCommand() bool {
+	exports := c.compiled.ExportedFunctions()
+	_, hasTick := exports["go_tick"]
+	_, hasStartMain := exports["go_start_main"]
+	_, hasStart := exports["_start"]
+	return hasStart && !(hasTick && hasStartMain)
+}
+
+// InstantiateRunner instantiates the module as a *Reactor, or as a
+// *CommandReactor if IsCommand reports true. Reactor-only settings in cfg are
+// ignored for commands.
+func (c *CompiledReactor) InstantiateRunner(ctx context.Context, cfg *Config) (Runner, error) {
+	if !c.IsCommand() {
+		return c.Instantiate(ctx, cfg)
+	}
+
+	if cfg == nil {
+		cfg = &Config{}
+	}
+	stdin, stdout, stderr := cfg.stdio()
+	mod, err := c.instantiate(ctx, cfg, stdin, stdout, stderr, cfg.newHostState())
+	if err != nil {
+		return nil, err
+	}
+	return &CommandReactor{mod: mod, start: mod.ExportedFunction("_start")}, nil
+}
+
+// Run calls _start and blocks until the program finishes. It returns nil if
+// the program exits with code zero, and the *sys.ExitError otherwise.
+// A command can only be run once.
+func (c *CommandReactor) Run(ctx context.Context) error {
+	if !c.ran.CompareAndSwap(false, true) {
+		return errors.New("command module has already run")
+	}
+
+	_, err := c.start.Call(ctx)
+	var exitErr *sys.ExitError
+	if errors.As(err, &exitErr) {
+		c.exitCode.Store(exitErr.ExitCode())
+		if exitErr.ExitCode() == 0 {
+			return nil
+		}
+	}
+	return err
+}
+
+// ExitCode returns the code the program exited with, or zero if it returned
+// from _start normally or has not run.
+func (c *CommandReactor) ExitCode() uint32 {
+	return c.exitCode.Load()
+}
+
+// Close releases resources associated with the module.
+func (c *CommandReactor) Close(ctx context.Context) error {
+	return c.mod.Close(ctx)
+}
+
+// Module returns the underlying wazero module for advanced usage.
+func (c *CommandReactor) Module() api.Module {
+	return c.mod
+}
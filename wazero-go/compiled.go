@@ -0,0 +1,50 @@
+package reactor
+
+import (
+	"context"
+	"crypto/sha256"
+
+	"github.com/tetratelabs/wazero"
+)
+
+// CompiledReactor is a compiled Go WASI reactor module. It can be instantiated
+// any number of times on the runtime it was compiled with, avoiding repeated
+// compilation when running many reactors from the same wasm.
+type CompiledReactor struct {
+	runtime  wazero.Runtime
+	compiled wazero.CompiledModule
+	// hash is the SHA-256 of the wasm, identifying it in snapshots and
+	// checkpoints.
+	hash [sha256.Size]byte
+	// warnings are compatibility notes found at compile time; see Warnings.
+	warnings []string
+}
+
+// Compile compiles wasm for use with the runtime r.
+// Returns a *CompileError if compilation fails. Problems that do not prevent
+// compilation are reported by Warnings.
+func Compile(ctx context.Context, r wazero.Runtime, wasm []byte) (*CompiledReactor, error) {
+	compiled, err := r.CompileModule(ctx, wasm)
+	if err != nil {
+		return nil, &CompileError{Err: err}
+	}
+	c := &CompiledReactor{runtime: r, compiled: compiled, hash: sha256.Sum256(wasm)}
+	c.warnings = c.compatibilityWarnings()
+	return c, nil
+}
+
+// Runtime returns the runtime the module was compiled with.
+func (c *CompiledReactor) Runtime() wazero.Runtime {
+	return c.runtime
+}
+
+// Module returns the underlying wazero compiled module for advanced usage.
+func (c *CompiledReactor) Module() wazero.CompiledModule {
+	return c.compiled
+}
+
+// Close releases the compiled module. Reactors already instantiated from it
+// are unaffected.
+func (c *CompiledReactor) Close(ctx context.Context) error {
+	return c.compiled.Close(ctx)
+}
@@ -0,0 +1,49 @@
+package reactor
+
+import (
+	"context"
+	"net"
+)
+
+// ServeConn runs a reactor for one network connection: reads from conn are
+// the guest's stdin and the guest's stdout is written to conn. It instantiates
+// compiled, runs the guest until it goes idle or exits, and closes both the
+// reactor and conn before returning. Other settings are taken from cfg.
+//
+// If the peer half-closes the connection, the guest reads EOF from stdin but
+// can keep writing its response. Once the guest is done the write side is shut
+// down first, if conn supports CloseWrite as *net.TCPConn does, so the peer
+// sees a clean EOF. If ctx is done, conn is closed to unblock a guest waiting
+// on a read, which then sees an error.
+//
+// As with Filter, a guest exiting via proc_exit is reported in the outcome
+// rather than as an error.
+func ServeConn(ctx context.Context, compiled *CompiledReactor, conn net.Conn, cfg *Config) (RunOutcome, error) {
+	defer conn.Close()
+
+	var c Config
+	if cfg != nil {
+		c = *cfg
+	}
+	c.Stdin, c.StdinFunc = conn, nil
+	c.Stdout = conn
+
+	r, err := compiled.Instantiate(ctx, &c)
+	if err != nil {
+		return RunOutcome{}, err
+	}
+	defer r.Close(ctx)
+
+	stop := context.AfterFunc(ctx, func() { _ = conn.Close() })
+	defer stop()
+
+	err = r.Run(ctx)
+	out := r.Outcome()
+	if out.Exited {
+		err = nil
+	}
+	if cw, ok := conn.(interface{ CloseWrite() error }); ok && err == nil {
+		_ = cw.CloseWrite()
+	}
+	return out, err
+}
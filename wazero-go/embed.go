@@ -0,0 +1,38 @@
+package reactor
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/tetratelabs/wazero"
+)
+
+// EmbedFS returns an FSConfig mounting the directory root of efs read-only at
+// the guest's root, for use as Config.FS.
+//
+// Paths in an embed.FS keep the directory they were embedded from, e.g.
+// "assets/img.png" for //go:embed assets, so root is usually that directory:
+// the guest then opens "/img.png". root may be "." to mount efs as a whole;
+// leading "./" and "/" are ignored. An error is returned if root is not a
+// directory in efs.
+func EmbedFS(efs embed.FS, root string) (wazero.FSConfig, error) {
+	root = strings.TrimLeft(path.Clean("/"+root), "/")
+	if root == "" {
+		root = "."
+	}
+	info, err := fs.Stat(efs, root)
+	if err != nil {
+		return nil, fmt.Errorf("embed root: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("embed root %s is not a directory", root)
+	}
+	sub, err := fs.Sub(efs, root)
+	if err != nil {
+		return nil, fmt.Errorf("embed root: %w", err)
+	}
+	return wazero.NewFSConfig().WithFSMount(sub, "/"), nil
+}
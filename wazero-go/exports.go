@@ -0,0 +1,35 @@
+package reactor
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ErrDisallowedExport is returned when the guest exports a function not in
+// Config.AllowedExports. The wrapping error lists the offending exports.
+var ErrDisallowedExport = errors.New("module exports disallowed functions")
+
+// abiExports are the exports every reactor must have, and so are always
+// allowed.
+var abiExports = []string{"_initialize", "go_start_main", "go_tick"}
+
+// checkExports returns ErrDisallowedExport if allowed is set and the module
+// exports functions outside it and the reactor ABI.
+func (c *CompiledReactor) checkExports(allowed []string) error {
+	if allowed == nil {
+		return nil
+	}
+	var offenders []string
+	for name := range c.compiled.ExportedFunctions() {
+		if !slices.Contains(abiExports, name) && !slices.Contains(allowed, name) {
+			offenders = append(offenders, name)
+		}
+	}
+	if len(offenders) == 0 {
+		return nil
+	}
+	slices.Sort(offenders)
+	return fmt.Errorf("%w: %s", ErrDisallowedExport, strings.Join(offenders, ", "))
+}
@@ -0,0 +1,41 @@
+package reactor
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/tetratelabs/wazero/api"
+)
+
+func TestAllowedExports(t *testing.T) {
+	extra := testFunc{export: "go_extra", results: []api.ValueType{api.ValueTypeI32}, body: i32Const(0)}
+	tests := []struct {
+		name    string
+		m       testModule
+		allowed []string
+		wantErr bool
+	}{
+		{"unrestricted", reactorModule(i32Const(int32(LoopIdle)), extra), nil, false},
+		{"abi only", reactorModule(i32Const(int32(LoopIdle))), []string{}, false},
+		{"extra allowed", reactorModule(i32Const(int32(LoopIdle)), extra), []string{"go_extra"}, false},
+		{"extra disallowed", reactorModule(i32Const(int32(LoopIdle)), extra), []string{"go_wants_tick"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newReactor(t, tt.m.encode(), &Config{AllowedExports: tt.allowed})
+			if !tt.wantErr {
+				if err != nil {
+					t.Fatalf("new reactor: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrDisallowedExport) {
+				t.Fatalf("got %v, want ErrDisallowedExport", err)
+			}
+			if !strings.Contains(err.Error(), "go_extra") {
+				t.Errorf("error %q does not name the disallowed export", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package reactor
+
+import (
+	"context"
+	"io"
+	"strings"
+)
+
+// Filter runs a reactor as a byte transformer: input is the guest's stdin and
+// its stdout is written to output. It instantiates compiled, runs the guest to
+// completion, closes it, and returns how it finished.
+//
+// The guest reads input on demand, so short reads and EOF are seen by the
+// guest exactly as the reader returns them. If input is nil the guest reads
+// an empty stdin. Other settings, such as Stderr and Args, are taken from cfg.
+//
+// A guest exiting via proc_exit is reported in the outcome rather than as an
+// error, so a non-zero exit code with a nil error means the guest ran and
+// failed on its own terms.
+func Filter(ctx context.Context, compiled *CompiledReactor, input io.Reader, output io.Writer, cfg *Config) (RunOutcome, error) {
+	var c Config
+	if cfg != nil {
+		c = *cfg
+	}
+	if input == nil {
+		input = strings.NewReader("")
+	}
+	c.Stdin, c.StdinFunc = input, nil
+	c.Stdout = output
+
+	r, err := compiled.Instantiate(ctx, &c)
+	if err != nil {
+		return RunOutcome{}, err
+	}
+	defer r.Close(ctx)
+
+	err = r.Run(ctx)
+	out := r.Outcome()
+	if out.Exited {
+		err = nil
+	}
+	return out, err
+}
@@ -0,0 +1,57 @@
+package reactor
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// RunFixedRate runs the reactor like Run, but calls go_tick exactly once per
+// interval, e.g. every 16.7ms for 60Hz, regardless of what the guest reports:
+// LoopReady does not tick again early and timers are not waited on, so guest
+// timers effectively fire at the next frame. This models frame-based
+// execution, such as a game or simulation loop, or rate-limits a guest. It
+// returns when the guest goes idle or exits, or ctx is done.
+//
+// A tick that takes longer than interval delays the frames after it, which are
+// not made up: the guest then runs fewer frames than wall time allows and its
+// work lags behind. Each overrun is logged at warn level with how far the tick
+// overran.
+func (r *Reactor) RunFixedRate(ctx context.Context, interval time.Duration) error {
+	if interval <= 0 {
+		return fmt.Errorf("fixed rate interval must be positive, got %v", interval)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if !r.cfg.ManualStart {
+		if err := r.StartMain(ctx); err != nil {
+			return fmt.Errorf("start main: %w", err)
+		}
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		start := time.Now()
+		result, err := r.LoopOnce(ctx)
+		if err != nil {
+			return fmt.Errorf("loop once: %w", err)
+		}
+		if _, err := r.afterTick(ctx, result); err != nil {
+			return err
+		}
+		if result == LoopIdle {
+			return nil
+		}
+		if lag := time.Since(start) - interval; lag > 0 {
+			r.logger().Warn("fixed-rate tick overran interval", "interval", interval, "lag", lag)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
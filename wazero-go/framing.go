@@ -0,0 +1,68 @@
+package reactor
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// DefaultMaxFrameSize is the frame size limit used by ReceiveMessage when
+// maxSize is zero.
+const DefaultMaxFrameSize = 16 << 20
+
+// ErrFrameTooLarge is returned by ReceiveMessage when a frame's length prefix
+// exceeds the allowed maximum.
+var ErrFrameTooLarge = errors.New("frame exceeds maximum size")
+
+// SendMessage writes msg to w as a single length-prefixed frame.
+//
+// A frame is a 4-byte little-endian unsigned length followed by that many
+// payload bytes. Guests use the same layout on their side of the byte channel:
+//
+//	var hdr [4]byte
+//	binary.LittleEndian.PutUint32(hdr[:], uint32(len(msg)))
+//	w.Write(append(hdr[:], msg...))
+//
+// The frame is written with a single Write call so concurrent senders on a
+// writer that serializes writes do not interleave frames.
+func SendMessage(w io.Writer, msg []byte) error {
+	if uint64(len(msg)) > uint64(^uint32(0)) {
+		return ErrFrameTooLarge
+	}
+	frame := make([]byte, 4+len(msg))
+	binary.LittleEndian.PutUint32(frame, uint32(len(msg)))
+	copy(frame[4:], msg)
+	_, err := w.Write(frame)
+	return err
+}
+
+// ReceiveMessage reads one length-prefixed frame written by SendMessage.
+//
+// Frames longer than maxSize are rejected with ErrFrameTooLarge before any
+// payload is allocated, guarding against hostile length prefixes. If maxSize
+// is zero, DefaultMaxFrameSize is used. Returns io.EOF if r is at EOF before
+// the frame starts and io.ErrUnexpectedEOF if it ends mid-frame.
+func ReceiveMessage(r io.Reader, maxSize uint32) ([]byte, error) {
+	if maxSize == 0 {
+		maxSize = DefaultMaxFrameSize
+	}
+
+	var hdr [4]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return nil, err
+	}
+	n := binary.LittleEndian.Uint32(hdr[:])
+	if n > maxSize {
+		return nil, fmt.Errorf("%w: %d > %d", ErrFrameTooLarge, n, maxSize)
+	}
+
+	msg := make([]byte, n)
+	if _, err := io.ReadFull(r, msg); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return msg, nil
+}
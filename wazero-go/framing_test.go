@@ -0,0 +1,60 @@
+package reactor
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestMessageRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	msgs := [][]byte{[]byte("hello"), {}, bytes.Repeat([]byte{0xab}, 1000)}
+	for _, msg := range msgs {
+		if err := SendMessage(&buf, msg); err != nil {
+			t.Fatalf("send: %v", err)
+		}
+	}
+	for _, want := range msgs {
+		got, err := ReceiveMessage(&buf, 0)
+		if err != nil {
+			t.Fatalf("receive: %v", err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("got %d bytes, want %d", len(got), len(want))
+		}
+	}
+	if _, err := ReceiveMessage(&buf, 0); err != io.EOF {
+		t.Errorf("receive at end: got %v, want io.EOF", err)
+	}
+}
+
+func TestReceiveMessageErrors(t *testing.T) {
+	frame := func(n uint32, payload string) []byte {
+		b := binary.LittleEndian.AppendUint32(nil, n)
+		return append(b, payload...)
+	}
+	tests := []struct {
+		name    string
+		in      []byte
+		maxSize uint32
+		want    error
+	}{
+		{"oversize prefix", frame(11, "hello world"), 10, ErrFrameTooLarge},
+		{"hostile prefix", frame(^uint32(0), ""), 0, ErrFrameTooLarge},
+		{"at max size", frame(5, "hello"), 5, nil},
+		{"truncated payload", frame(10, "hello"), 0, io.ErrUnexpectedEOF},
+		{"missing payload", frame(10, ""), 0, io.ErrUnexpectedEOF},
+		{"truncated prefix", []byte{1, 0}, 0, io.ErrUnexpectedEOF},
+		{"empty", nil, 0, io.EOF},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ReceiveMessage(bytes.NewReader(tt.in), tt.maxSize)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("got %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package reactor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/tetratelabs/wazero/api"
+)
+
+// ErrGoroutineLeak is returned by CheckGoroutineLeak when the guest has more
+// goroutines than its baseline.
+var ErrGoroutineLeak = errors.New("guest goroutine leak")
+
+// GoroutineCount returns the number of goroutines in the guest, as reported
+// by its optional go_num_goroutine export (runtime.NumGoroutine). The second
+// result is false if the guest does not export it or the call fails. It must
+// not be called concurrently with a tick.
+func (r *Reactor) GoroutineCount(ctx context.Context) (int, bool) {
+	fn := r.mod.ExportedFunction("go_num_goroutine")
+	if fn == nil {
+		return 0, false
+	}
+	results, err := r.call(ctx, fn)
+	if err != nil {
+		return 0, false
+	}
+	return int(api.DecodeI32(results[0])), true
+}
+
+// CheckGoroutineLeak reports whether the guest's goroutine count has returned
+// to baseline, typically a GoroutineCount taken before a logical request, to
+// catch guests that leak goroutines across requests of a reused reactor.
+// Goroutines only finish when ticked, so call it once the request is done and
+// the guest has gone idle.
+//
+// It returns an error wrapping ErrGoroutineLeak if the count is above
+// baseline, and ErrUnsupported if the guest does not report its count.
+func (r *Reactor) CheckGoroutineLeak(ctx context.Context, baseline int) error {
+	n, ok := r.GoroutineCount(ctx)
+	if !ok {
+		return fmt.Errorf("%w: missing export go_num_goroutine", ErrUnsupported)
+	}
+	if n > baseline {
+		return fmt.Errorf("%w: %d goroutines, baseline %d", ErrGoroutineLeak, n, baseline)
+	}
+	return nil
+}
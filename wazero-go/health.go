@@ -0,0 +1,65 @@
+package reactor
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// Health is a snapshot of a reactor's liveness.
+type Health struct {
+	// Alive is false once the reactor has been closed or the guest has
+	// exited, trapped or been interrupted, so it can tick no more.
+	Alive bool `json:"alive"`
+	// State is one of "starting", "idle", "ready", "waiting", or "closed",
+	// the last whenever Alive is false.
+	State string `json:"state"`
+	// Ticks is the number of completed go_tick calls.
+	Ticks uint64 `json:"ticks"`
+	// LastTick is the time of the last go_tick, nil if none has run.
+	LastTick *time.Time `json:"lastTick,omitempty"`
+	// UptimeMs is the time since the reactor was created in milliseconds.
+	UptimeMs int64 `json:"uptimeMs"`
+}
+
+// Health returns a snapshot of the reactor's liveness.
+// It is safe to call concurrently with the run loop.
+func (r *Reactor) Health() Health {
+	h := Health{
+		Alive:    r.terminated() == nil,
+		Ticks:    r.ticks.Load(),
+		UptimeMs: time.Since(r.created).Milliseconds(),
+	}
+	if last := r.lastTick.Load(); last != 0 {
+		t := time.Unix(0, last)
+		h.LastTick = &t
+	}
+
+	switch result := LoopResult(r.lastResult.Load()); {
+	case !h.Alive:
+		h.State = "closed"
+	case h.LastTick == nil:
+		h.State = "starting"
+	case result == LoopIdle:
+		h.State = "idle"
+	case result == LoopReady:
+		h.State = "ready"
+	default:
+		h.State = "waiting"
+	}
+	return h
+}
+
+// HealthHandler returns an http.Handler that reports the reactor's Health as
+// JSON. It responds 200 while the reactor is alive and 503 once it is not, so
+// it can be used directly as a liveness probe.
+func HealthHandler(r *Reactor) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		h := r.Health()
+		w.Header().Set("Content-Type", "application/json")
+		if !h.Alive {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		_ = json.NewEncoder(w).Encode(h)
+	})
+}
@@ -0,0 +1,41 @@
+package reactor
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHealthHandlerAfterTrap(t *testing.T) {
+	r := newTestReactor(t, reactorModule([]byte{0x00}), nil) // unreachable
+	h := HealthHandler(r)
+	get := func() (int, Health) {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		var health Health
+		if err := json.Unmarshal(rec.Body.Bytes(), &health); err != nil {
+			t.Fatalf("decode health: %v", err)
+		}
+		return rec.Code, health
+	}
+	if code, _ := get(); code != http.StatusOK {
+		t.Fatalf("status before tick = %d, want %d", code, http.StatusOK)
+	}
+
+	ctx := context.Background()
+	if err := r.StartMain(ctx); err != nil {
+		t.Fatalf("start main: %v", err)
+	}
+	if _, err := r.LoopOnce(ctx); err == nil {
+		t.Fatal("tick did not trap")
+	}
+	code, health := get()
+	if code != http.StatusServiceUnavailable {
+		t.Errorf("status after trap = %d, want %d", code, http.StatusServiceUnavailable)
+	}
+	if health.Alive || health.State != "closed" {
+		t.Errorf("health after trap = %+v, want not alive and closed", health)
+	}
+}
@@ -0,0 +1,169 @@
+package reactor
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync/atomic"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+)
+
+// HostFunction is a Go function exported to the guest.
+type HostFunction struct {
+	// Name is the name the guest imports the function by.
+	Name string
+	// Params are the wasm parameter types.
+	Params []api.ValueType
+	// Results are the wasm result types.
+	Results []api.ValueType
+	// Func implements the function. Parameters are read from and results
+	// written to stack, as with api.GoModuleFunc.
+	Func api.GoModuleFunc
+}
+
+// HostModule is a named set of host functions the guest can import.
+type HostModule struct {
+	// Name is the import module name, e.g. "env".
+	Name string
+	// Functions are the functions exported by the module.
+	Functions []HostFunction
+}
+
+// HostCallbackError reports a panic raised by a host callback during a tick.
+// The reactor is closed when it is returned, as the guest was interrupted at
+// an arbitrary point and cannot safely continue.
+type HostCallbackError struct {
+	// Name identifies the callback, e.g. "env.host_log" or "onTick".
+	Name string
+	// Value is the recovered panic value.
+	Value any
+	// Stack is the Go stack captured when the panic was recovered.
+	Stack []byte
+}
+
+// Error implements error.
+func (e *HostCallbackError) Error() string {
+	return fmt.Sprintf("host callback %s panicked: %v", e.Name, e.Value)
+}
+
+// Unwrap returns the panic value if it was an error.
+func (e *HostCallbackError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// instantiateHostModules instantiates the host modules into the runtime.
+// Modules sharing a name are merged. A module already in the runtime, e.g.
+// from an earlier reactor, is reused if it exports every function asked for;
+// otherwise an error is returned, as the runtime cannot hold a second module
+// of the same name and the guest would fail to link.
+func instantiateHostModules(ctx context.Context, r wazero.Runtime, mods []HostModule) error {
+	var names []string
+	builders := make(map[string]wazero.HostModuleBuilder)
+	for _, m := range mods {
+		if existing := r.Module(m.Name); existing != nil {
+			for _, fn := range m.Functions {
+				if existing.ExportedFunction(fn.Name) == nil {
+					return fmt.Errorf("host module %s is already instantiated without %s", m.Name, fn.Name)
+				}
+			}
+			continue
+		}
+		b, ok := builders[m.Name]
+		if !ok {
+			b = r.NewHostModuleBuilder(m.Name)
+			builders[m.Name] = b
+			names = append(names, m.Name)
+		}
+		for _, fn := range m.Functions {
+			b.NewFunctionBuilder().
+				WithGoModuleFunction(recoverHostFunc(m.Name+"."+fn.Name, fn.Func), fn.Params, fn.Results).
+				Export(fn.Name)
+		}
+	}
+
+	for _, name := range names {
+		if _, err := builders[name].Instantiate(ctx); err != nil {
+			return fmt.Errorf("instantiate host module %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// hostModules returns the configured host modules plus enabled built-ins.
+func (cfg *Config) hostModules() []HostModule {
+	mods := cfg.HostModules
+	if cfg.HostSeq || cfg.Secrets != nil {
+		mods = append(mods[:len(mods):len(mods)], builtinEnvModule)
+	}
+	if cfg.Metrics != nil {
+		mods = append(mods[:len(mods):len(mods)], metricsModule)
+	}
+	return mods
+}
+
+// hostSeq is the counter behind env.host_seq.
+var hostSeq atomic.Uint64
+
+// builtinEnvModule holds every built-in env import, so whichever built-in
+// the first reactor on a runtime enables, the env module it instantiates
+// serves later reactors enabling the others. Each import checks the calling
+// reactor's own configuration where that matters.
+var builtinEnvModule = HostModule{
+	Name:      "env",
+	Functions: []HostFunction{hostSeqFunc, getSecretFunc},
+}
+
+// hostSeqFunc implements env.host_seq() i64, see Config.HostSeq.
+var hostSeqFunc = HostFunction{
+	Name:    "host_seq",
+	Results: []api.ValueType{api.ValueTypeI64},
+	Func: func(_ context.Context, _ api.Module, stack []uint64) {
+		stack[0] = hostSeq.Add(1)
+	},
+}
+
+// callerKey is the context key for the *Reactor making a guest call.
+type callerKey struct{}
+
+// withCaller attaches the reactor to ctx for the duration of a guest call, if
+// it has state that host functions shared across reactors must find, such as
+// WASI hooks, secrets, or metrics. Other reactors skip the allocation.
+func (r *Reactor) withCaller(ctx context.Context) context.Context {
+	if r.wasiHooks == nil && r.secrets == nil && r.metricNames == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, callerKey{}, r)
+}
+
+// callerFromContext returns the reactor attached by withCaller, or nil.
+func callerFromContext(ctx context.Context) *Reactor {
+	r, _ := ctx.Value(callerKey{}).(*Reactor)
+	return r
+}
+
+// recoverHostFunc converts panics in fn into a HostCallbackError panic, which
+// wazero surfaces as the error returned by the guest call.
+func recoverHostFunc(name string, fn api.GoModuleFunc) api.GoModuleFunc {
+	return func(ctx context.Context, mod api.Module, stack []uint64) {
+		defer func() {
+			if v := recover(); v != nil {
+				panic(&HostCallbackError{Name: name, Value: v, Stack: debug.Stack()})
+			}
+		}()
+		fn(ctx, mod, stack)
+	}
+}
+
+// callHostCallback calls fn, converting a panic into a HostCallbackError.
+func callHostCallback(name string, fn func()) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = &HostCallbackError{Name: name, Value: v, Stack: debug.Stack()}
+		}
+	}()
+	fn()
+	return nil
+}
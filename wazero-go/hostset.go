@@ -0,0 +1,73 @@
+package reactor
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+)
+
+// HostModuleSet is a set of host modules defined once and shared by many
+// reactors, e.g. the host API of a plugin system. It is instantiated at most
+// once per runtime, when the first reactor using it is created there, and
+// reused by every later reactor on that runtime. See Config.HostModuleSet.
+//
+// Unlike Config.HostModules, which reuses a module of the same name already
+// in the runtime if it exports the functions asked for, a set refuses to share
+// a runtime with modules of the same name it did not instantiate, so plugins
+// cannot silently bind to a different host API.
+type HostModuleSet struct {
+	modules []HostModule
+
+	mu sync.Mutex
+	// instances are the modules instantiated per runtime. Entries live as
+	// long as the set, so a set should not outlive many runtimes.
+	instances map[wazero.Runtime][]api.Module
+}
+
+// NewHostModuleSet constructs a HostModuleSet from the given modules. Modules
+// sharing a name are merged.
+func NewHostModuleSet(modules ...HostModule) *HostModuleSet {
+	return &HostModuleSet{
+		modules:   modules,
+		instances: make(map[wazero.Runtime][]api.Module),
+	}
+}
+
+// instantiate instantiates the set into r unless it already has been.
+func (s *HostModuleSet) instantiate(ctx context.Context, r wazero.Runtime) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if mods, ok := s.instances[r]; ok {
+		for _, mod := range mods {
+			if r.Module(mod.Name()) != mod {
+				return fmt.Errorf("host module %s of set was replaced in runtime", mod.Name())
+			}
+		}
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	var names []string
+	for _, m := range s.modules {
+		if r.Module(m.Name) != nil {
+			return fmt.Errorf("host module %s already instantiated outside the set", m.Name)
+		}
+		if !seen[m.Name] {
+			seen[m.Name] = true
+			names = append(names, m.Name)
+		}
+	}
+	if err := instantiateHostModules(ctx, r, s.modules); err != nil {
+		return err
+	}
+	mods := make([]api.Module, len(names))
+	for i, name := range names {
+		mods[i] = r.Module(name)
+	}
+	s.instances[r] = mods
+	return nil
+}
@@ -0,0 +1,48 @@
+package reactor
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+)
+
+func TestHostModuleSetShared(t *testing.T) {
+	var calls int
+	set := NewHostModuleSet(HostModule{
+		Name: "test",
+		Functions: []HostFunction{{
+			Name: "count",
+			Func: func(context.Context, api.Module, []uint64) { calls++ },
+		}},
+	})
+	m := reactorModule(append(callFunc(0), i32Const(int32(LoopIdle))...))
+	m.imports = []testImport{{module: "test", name: "count"}}
+
+	ctx := context.Background()
+	rt := wazero.NewRuntime(ctx)
+	defer rt.Close(ctx)
+	var host api.Module
+	for i := range 2 {
+		r, err := NewReactor(ctx, rt, m.encode(), &Config{Name: fmt.Sprintf("guest%d", i), HostModuleSet: set})
+		if err != nil {
+			t.Fatalf("reactor %d: %v", i, err)
+		}
+		if i == 0 {
+			host = rt.Module("test")
+		} else if rt.Module("test") != host {
+			t.Error("second reactor replaced the set's host module")
+		}
+		if err := r.Run(ctx); err != nil {
+			t.Fatalf("run reactor %d: %v", i, err)
+		}
+	}
+	if calls != 2 {
+		t.Errorf("host function called %d times, want 2", calls)
+	}
+	if n := len(set.instances); n != 1 {
+		t.Errorf("set instantiated on %d runtimes, want 1", n)
+	}
+}
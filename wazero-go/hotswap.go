@@ -0,0 +1,63 @@
+package reactor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// HotSwapDrainTimeout bounds how long HotSwap drains the old reactor before
+// closing it regardless, so a guest waiting on the host cannot stall the swap.
+const HotSwapDrainTimeout = 5 * time.Second
+
+// HotSwap replaces old with a new reactor instantiated from wasm on the same
+// runtime, for zero-downtime upgrades.
+//
+// The new reactor is created first; if that fails the error is returned and
+// old is left untouched. old is then drained as by Run, ticking it until it
+// goes idle or exits, for at most HotSwapDrainTimeout or old's
+// MaxRunDuration, whichever is shorter, and closed. The caller must stop
+// driving old (e.g. return from its Run) before calling HotSwap, and start
+// the new reactor with Run or Serve afterwards. A guest that was never
+// started, or has already exited, is closed without draining.
+//
+// If cfg is nil, old's Config is reused, except for its Store, so the new
+// module neither restores nor overwrites the old one's snapshots. Host-side
+// resources referenced by the Config, such as Stdin, Stdout, Stderr, FS,
+// HostModules, and Registry, are thereby handed to the new reactor. Guest
+// state held in linear memory (heap objects, goroutines, timers, open guest
+// file descriptors) does not migrate; the new guest starts fresh from
+// _initialize.
+//
+// Once the new reactor exists the swap has succeeded, so errors draining or
+// closing old do not fail it: they are logged to old's Logger.
+func HotSwap(ctx context.Context, old *Reactor, wasm []byte, cfg *Config) (*Reactor, error) {
+	if cfg == nil {
+		oldCfg := old.cfg
+		oldCfg.Store = nil
+		cfg = &oldCfg
+	}
+
+	next, err := NewReactor(ctx, old.runtime, wasm, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("create replacement: %w", err)
+	}
+
+	if old.started.Load() && old.terminated() == nil {
+		drainCtx, cancel := context.WithTimeout(ctx, HotSwapDrainTimeout)
+		err := old.run(drainCtx, nil, false)
+		cancel()
+		switch {
+		case err == nil, old.HasExited():
+		case errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil:
+			old.logger().Info("hot swap drain timed out, closing old reactor", "reactor", old.cfg.Name)
+		default:
+			old.logger().Warn("hot swap drain failed", "reactor", old.cfg.Name, "error", err)
+		}
+	}
+	if err := old.Close(ctx); err != nil {
+		old.logger().Warn("hot swap close failed", "reactor", old.cfg.Name, "error", err)
+	}
+	return next, nil
+}
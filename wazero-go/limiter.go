@@ -0,0 +1,37 @@
+package reactor
+
+import "context"
+
+// Limiter bounds how many reactors sharing it instantiate and run _initialize
+// at once, queueing the rest, to smooth out the memory and CPU spike of a
+// burst of cold starts. See Config.InstantiateLimiter. A Limiter is safe for
+// concurrent use.
+type Limiter struct {
+	slots chan struct{}
+}
+
+// NewLimiter returns a Limiter allowing n concurrent instantiations, at least
+// one.
+func NewLimiter(n int) *Limiter {
+	return &Limiter{slots: make(chan struct{}, max(n, 1))}
+}
+
+// Acquire waits for a free slot, returning ctx's error if it is done first.
+func (l *Limiter) Acquire(ctx context.Context) error {
+	select {
+	case l.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Release frees a slot taken by Acquire.
+func (l *Limiter) Release() {
+	<-l.slots
+}
+
+// InUse returns the number of slots currently taken.
+func (l *Limiter) InUse() int {
+	return len(l.slots)
+}
@@ -0,0 +1,68 @@
+package reactor
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLimiterCap(t *testing.T) {
+	const limit, workers = 3, 20
+	l := NewLimiter(limit)
+	var inUse, peak atomic.Int32
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := l.Acquire(context.Background()); err != nil {
+				t.Errorf("acquire: %v", err)
+				return
+			}
+			n := inUse.Add(1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			inUse.Add(-1)
+			l.Release()
+		}()
+	}
+	wg.Wait()
+
+	if p := peak.Load(); p > limit {
+		t.Errorf("%d acquired at once, want at most %d", p, limit)
+	}
+	if n := l.InUse(); n != 0 {
+		t.Errorf("InUse = %d after every release, want 0", n)
+	}
+}
+
+func TestLimiterAcquireCanceled(t *testing.T) {
+	l := NewLimiter(1)
+	if err := l.Acquire(context.Background()); err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := l.Acquire(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("acquire while full: got %v, want context.DeadlineExceeded", err)
+	}
+	if n := l.InUse(); n != 1 {
+		t.Errorf("InUse = %d, want 1", n)
+	}
+}
+
+func TestLimiterMinimum(t *testing.T) {
+	l := NewLimiter(0)
+	if err := l.Acquire(context.Background()); err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+	l.Release()
+}
@@ -0,0 +1,63 @@
+package reactor
+
+import (
+	"bytes"
+	"io"
+)
+
+// LineEndingMode selects which guest output Config.NormalizeLineEndings
+// applies to. Modes may be combined.
+type LineEndingMode uint8
+
+const (
+	// NormalizeCaptured normalizes the stdout and stderr kept by
+	// Config.CaptureOutput, as returned by DrainStdout and DrainStderr.
+	NormalizeCaptured LineEndingMode = 1 << iota
+	// NormalizeStdout normalizes what is written to Config.Stdout.
+	NormalizeStdout
+	// NormalizeStderr normalizes what is written to Config.Stderr.
+	NormalizeStderr
+)
+
+// lineNormalizer converts CRLF to LF in what is written through it. A CR at
+// the end of a write is held back until the next shows whether an LF follows.
+type lineNormalizer struct {
+	w  io.Writer
+	cr bool
+}
+
+// Write implements io.Writer.
+func (n *lineNormalizer) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	total := len(p)
+	out := make([]byte, 0, len(p)+1)
+	if n.cr && p[0] != '\n' {
+		out = append(out, '\r')
+	}
+	n.cr = p[len(p)-1] == '\r'
+	if n.cr {
+		p = p[:len(p)-1]
+	}
+	out = append(out, bytes.ReplaceAll(p, []byte("\r\n"), []byte("\n"))...)
+	if _, err := n.w.Write(out); err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+// Flush writes a held back CR, then flushes the underlying writer if it is a
+// Flusher.
+func (n *lineNormalizer) Flush() error {
+	if n.cr {
+		n.cr = false
+		if _, err := n.w.Write([]byte{'\r'}); err != nil {
+			return err
+		}
+	}
+	if f, ok := n.w.(Flusher); ok {
+		return f.Flush()
+	}
+	return nil
+}
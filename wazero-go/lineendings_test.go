@@ -0,0 +1,55 @@
+package reactor
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestLineNormalizer(t *testing.T) {
+	tests := []struct {
+		name   string
+		writes []string
+		want   string
+	}{
+		{"lf only", []string{"a\nb\n"}, "a\nb\n"},
+		{"crlf", []string{"a\r\nb\r\n"}, "a\nb\n"},
+		{"mixed", []string{"a\r\nb\nc\rd\r\n"}, "a\nb\nc\rd\n"},
+		{"cr split from lf", []string{"a\r", "\nb"}, "a\nb"},
+		{"cr split from text", []string{"a\r", "b"}, "a\rb"},
+		{"cr across empty write", []string{"a\r", "", "\n"}, "a\n"},
+		{"cr cr lf", []string{"a\r\r", "\n"}, "a\r\n"},
+		{"trailing cr", []string{"a\r"}, "a\r"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			n := &lineNormalizer{w: &buf}
+			for _, w := range tt.writes {
+				if got, err := n.Write([]byte(w)); err != nil || got != len(w) {
+					t.Fatalf("write %q = %d, %v", w, got, err)
+				}
+			}
+			if err := n.Flush(); err != nil {
+				t.Fatalf("flush: %v", err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCaptureWriterFlushNormalizer(t *testing.T) {
+	buf := &captureBuffer{}
+	c := &captureWriter{w: io.Discard, buf: buf, norm: &lineNormalizer{w: buf}}
+	if _, err := c.Write([]byte("a\r\nb\r")); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if err := c.Flush(); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+	if got := string(buf.drain()); got != "a\nb\r" {
+		t.Errorf("captured %q, want %q", got, "a\nb\r")
+	}
+}
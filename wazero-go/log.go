@@ -0,0 +1,30 @@
+package reactor
+
+import (
+	"context"
+	"log/slog"
+)
+
+// discardHandler is a slog.Handler that drops all records.
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (h discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h discardHandler) WithGroup(string) slog.Handler           { return h }
+
+// discardLogger is used when Config.Logger is nil.
+var discardLogger = slog.New(discardHandler{})
+
+// logger returns the reactor's logger, never nil.
+func (r *Reactor) logger() *slog.Logger {
+	return r.cfg.logger()
+}
+
+// logger returns the configured logger, never nil.
+func (cfg *Config) logger() *slog.Logger {
+	if cfg.Logger != nil {
+		return cfg.Logger
+	}
+	return discardLogger
+}
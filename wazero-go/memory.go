@@ -0,0 +1,77 @@
+package reactor
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// errMemoryClosed is returned by a memory reader once the reactor is closed.
+var errMemoryClosed = errors.New("reactor closed")
+
+// MemoryReaderAt returns an io.ReaderAt over the guest's linear memory, for
+// reading large results without first copying them out with a helper. Each
+// ReadAt copies straight from wazero's view of the memory into p, with offsets
+// being guest addresses; reads past the end of memory are short and return
+// io.EOF.
+//
+// The reader is only valid between ticks while the reactor is open: a tick may
+// grow the memory and so relocate its backing store, and memory being written
+// by a concurrent tick may be read torn. It returns an error once the reactor
+// is closed.
+func (r *Reactor) MemoryReaderAt() io.ReaderAt {
+	return memoryReader{r}
+}
+
+// memoryReader implements io.ReaderAt over a reactor's memory.
+type memoryReader struct {
+	r *Reactor
+}
+
+// ReadAt implements io.ReaderAt.
+func (m memoryReader) ReadAt(p []byte, off int64) (int, error) {
+	if m.r.closed.Load() {
+		return 0, errMemoryClosed
+	}
+	mem := m.r.mod.Memory()
+	if mem == nil {
+		return 0, io.EOF
+	}
+	if off < 0 {
+		return 0, errors.New("negative offset")
+	}
+	size := int64(mem.Size())
+	if off >= size {
+		return 0, io.EOF
+	}
+	n := min(int64(len(p)), size-off)
+	view, ok := mem.Read(uint32(off), uint32(n))
+	if !ok {
+		return 0, io.EOF
+	}
+	copy(p, view)
+	if int(n) < len(p) {
+		return int(n), io.EOF
+	}
+	return int(n), nil
+}
+
+// TrimMemory asks the guest to collect garbage and return free memory to its
+// allocator, through its optional go_gc export, to reduce the footprint of a
+// reactor left idle rather than torn down. It returns ErrUnsupported if the
+// guest does not export go_gc.
+//
+// This is best effort: wasm linear memory cannot shrink and wazero offers no
+// way to decommit part of it, so the host's RSS only drops as far as the
+// guest's own runtime releases pages. The GC costs time now, and the next
+// request may pay again to fault the freed memory back in, so trim reactors
+// expected to stay idle for a while rather than between every request. It
+// must not be called concurrently with a tick.
+func (r *Reactor) TrimMemory(ctx context.Context) error {
+	fn, err := r.optionalExport("go_gc")
+	if err != nil {
+		return err
+	}
+	_, err = r.call(ctx, fn)
+	return err
+}
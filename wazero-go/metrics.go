@@ -0,0 +1,113 @@
+package reactor
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/tetratelabs/wazero/api"
+)
+
+// MetricsModuleName is the name of the host module guests import to emit
+// metrics; see Config.Metrics.
+const MetricsModuleName = "reactor_metrics"
+
+// maxInternedNames bounds the metric names a reactor caches, so a guest
+// emitting unbounded distinct names cannot grow host memory without limit.
+const maxInternedNames = 1024
+
+// Collector receives metrics emitted by guests. Implementations typically
+// forward to the host's metrics system and must be safe for concurrent use
+// when shared by several reactors.
+type Collector interface {
+	// IncCounter adds delta to the named counter.
+	IncCounter(name string, delta int64)
+	// SetGauge sets the named gauge to value.
+	SetGauge(name string, value float64)
+}
+
+// WakeLatencyObserver is implemented by Collectors that want the reactor's
+// wake latency: the time from a guest timer falling due, or Notify being
+// called, to the next go_tick starting. It reveals host scheduling delays that
+// make guest timers fire late. Reactors observe it only when Config.Metrics
+// implements this interface.
+type WakeLatencyObserver interface {
+	// ObserveWakeLatency records one wake latency sample.
+	ObserveWakeLatency(d time.Duration)
+}
+
+// observeWake reports the wake latency of a tick starting at start, if a timer
+// fell due or Notify was called since the last tick.
+func (r *Reactor) observeWake(start time.Time) {
+	o, ok := r.cfg.Metrics.(WakeLatencyObserver)
+	if !ok {
+		return
+	}
+	due := r.timerDue.Swap(0)
+	if n := r.notifiedAt.Swap(0); n != 0 && (due == 0 || n < due) {
+		due = n
+	}
+	if due != 0 {
+		o.ObserveWakeLatency(max(start.Sub(time.Unix(0, due)), 0))
+	}
+}
+
+// metricNames interns metric names read from guest memory.
+type metricNames struct {
+	mu    sync.Mutex
+	names map[string]string
+}
+
+// intern returns b as a string, reusing an earlier copy if there is one.
+func (m *metricNames) intern(b []byte) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if s, ok := m.names[string(b)]; ok {
+		return s
+	}
+	s := string(b)
+	if len(m.names) < maxInternedNames {
+		m.names[s] = s
+	}
+	return s
+}
+
+// metricsModule provides the reactor_metrics imports, see Config.Metrics.
+var metricsModule = HostModule{
+	Name: MetricsModuleName,
+	Functions: []HostFunction{
+		{
+			Name:   "inc_counter",
+			Params: []api.ValueType{api.ValueTypeI32, api.ValueTypeI32, api.ValueTypeI64},
+			Func: func(ctx context.Context, mod api.Module, stack []uint64) {
+				if r, name, ok := metricName(ctx, mod, stack); ok {
+					r.cfg.Metrics.IncCounter(name, int64(stack[2]))
+				}
+			},
+		},
+		{
+			Name:   "set_gauge",
+			Params: []api.ValueType{api.ValueTypeI32, api.ValueTypeI32, api.ValueTypeF64},
+			Func: func(ctx context.Context, mod api.Module, stack []uint64) {
+				if r, name, ok := metricName(ctx, mod, stack); ok {
+					r.cfg.Metrics.SetGauge(name, api.DecodeF64(stack[2]))
+				}
+			},
+		},
+	},
+}
+
+// metricName returns the calling reactor and the metric name at the
+// (ptr, len) in the first two parameters. Calls from reactors without
+// Config.Metrics, or with an out of range name, are dropped.
+func metricName(ctx context.Context, mod api.Module, stack []uint64) (*Reactor, string, bool) {
+	r := callerFromContext(ctx)
+	if r == nil || r.metricNames == nil {
+		return nil, "", false
+	}
+	b, ok := mod.Memory().Read(api.DecodeU32(stack[0]), api.DecodeU32(stack[1]))
+	if !ok {
+		return nil, "", false
+	}
+	return r, r.metricNames.intern(b), true
+}
@@ -0,0 +1,41 @@
+package reactor
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// wakeRecorder is a Collector keeping wake latency samples.
+type wakeRecorder struct {
+	mu      sync.Mutex
+	samples []time.Duration
+}
+
+func (*wakeRecorder) IncCounter(string, int64) {}
+func (*wakeRecorder) SetGauge(string, float64) {}
+
+func (w *wakeRecorder) ObserveWakeLatency(d time.Duration) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.samples = append(w.samples, d)
+}
+
+func TestWakeLatencyMinTimerWait(t *testing.T) {
+	// The guest asks for 1ms, but MinTimerWait holds it back for 50ms; the
+	// wait is on time, not 49ms late
+	m := reactorModule(countTick(i32Const(1), LoopIdle))
+	m.globals = 1
+	rec := &wakeRecorder{}
+	r := newTestReactor(t, m, &Config{Metrics: rec, MinTimerWait: 50 * time.Millisecond})
+	waitRun(t, runAsync(r))
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	if len(rec.samples) != 1 {
+		t.Fatalf("got %d wake latency samples, want 1", len(rec.samples))
+	}
+	if d := rec.samples[0]; d > 25*time.Millisecond {
+		t.Errorf("wake latency %v, want it measured from the clamped deadline", d)
+	}
+}
@@ -0,0 +1,105 @@
+package reactor
+
+import (
+	"fmt"
+	"io/fs"
+	"sync"
+	"sync/atomic"
+
+	"github.com/tetratelabs/wazero"
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+	"github.com/tetratelabs/wazero/experimental/sysfs"
+)
+
+// Mount exposes a host directory or fs.FS to the guest. Unlike Config.FS,
+// files opened through Mounts are tracked by the reactor, so they count
+// toward Config.MaxOpenFiles and OpenFileCount.
+type Mount struct {
+	// GuestPath is where the mount appears in the guest, e.g. "/" or "/data".
+	GuestPath string
+	// Dir is the host directory to mount. Ignored if FS is set.
+	Dir string
+	// FS, if set, is mounted instead of Dir. It is always read-only.
+	FS fs.FS
+	// ReadOnly makes writes through the mount fail with EROFS.
+	ReadOnly bool
+}
+
+// sysFS returns the mount's file system.
+func (m Mount) sysFS() experimentalsys.FS {
+	if m.FS != nil {
+		return &sysfs.AdaptFS{FS: m.FS}
+	}
+	var fsys experimentalsys.FS = sysfs.DirFS(m.Dir)
+	if m.ReadOnly {
+		fsys = &sysfs.ReadFS{FS: fsys}
+	}
+	return fsys
+}
+
+// openFiles counts the files a guest has open through its Mounts.
+type openFiles struct {
+	n   atomic.Int64
+	max int64
+	// refused is set when an open is refused at max, for the WASI hooks to
+	// report it to the guest as EMFILE.
+	refused atomic.Bool
+}
+
+// withMounts adds mounts to fsConfig, creating one if nil, with every open
+// counted by files. It fails if fsConfig was not created by wazero, as only
+// its FSConfig supports mounting an experimental sys.FS.
+func withMounts(fsConfig wazero.FSConfig, mounts []Mount, files *openFiles) (wazero.FSConfig, error) {
+	if fsConfig == nil {
+		fsConfig = wazero.NewFSConfig()
+	}
+	for _, m := range mounts {
+		sysConfig, ok := fsConfig.(sysfs.FSConfig)
+		if !ok {
+			return nil, fmt.Errorf("mount %s: FS config %T does not support sys.FS mounts", m.GuestPath, fsConfig)
+		}
+		fsys := &countingFS{FS: m.sysFS(), files: files}
+		fsConfig = sysConfig.WithSysFSMount(fsys, m.GuestPath)
+	}
+	return fsConfig, nil
+}
+
+// countingFS counts open files and refuses opens beyond the limit.
+type countingFS struct {
+	experimentalsys.FS
+	files *openFiles
+}
+
+// OpenFile implements experimentalsys.FS.
+func (c *countingFS) OpenFile(path string, flag experimentalsys.Oflag, perm fs.FileMode) (experimentalsys.File, experimentalsys.Errno) {
+	if n := c.files.n.Add(1); c.files.max > 0 && n > c.files.max {
+		c.files.n.Add(-1)
+		c.files.refused.Store(true)
+		return nil, experimentalsys.EAGAIN
+	}
+	f, errno := c.FS.OpenFile(path, flag, perm)
+	if errno != 0 {
+		c.files.n.Add(-1)
+		return nil, errno
+	}
+	return &countingFile{File: f, files: c.files}, 0
+}
+
+// countingFile releases its slot in openFiles when closed.
+type countingFile struct {
+	experimentalsys.File
+	files *openFiles
+	once  sync.Once
+}
+
+// Close implements experimentalsys.File.
+func (c *countingFile) Close() experimentalsys.Errno {
+	c.once.Do(func() { c.files.n.Add(-1) })
+	return c.File.Close()
+}
+
+// OpenFileCount returns the number of files and directories the guest has
+// open through Config.Mounts, Config.OverlayFS, and Config.ProfileDir.
+func (r *Reactor) OpenFileCount() int {
+	return int(r.host.files.n.Load())
+}
@@ -0,0 +1,44 @@
+package reactor
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"testing/fstest"
+)
+
+func TestMaxOpenFiles(t *testing.T) {
+	// go_tick opens "f" in the mount at fd 3 until an open fails, counting
+	// opens in g0 and keeping the errno in g1
+	tick := slices.Concat(
+		[]byte{0x02, 0x40, 0x03, 0x40}, // block; loop
+		openFile(i32Const(1)),
+		[]byte{0x24, 0x01, 0x23, 0x01, 0x0d, 0x01},       // g1 = errno; br_if 1 (g1)
+		[]byte{0x23, 0x00, 0x41, 0x01, 0x6a, 0x24, 0x00}, // g0++
+		[]byte{0x0c, 0x00, 0x0b, 0x0b},                   // br 0; end; end
+		i32Const(int32(LoopIdle)),
+	)
+	m := reactorModule(tick)
+	m.imports = []testImport{pathOpen}
+	m.globals = 2
+	const limit = 5
+	r := newTestReactor(t, m, &Config{
+		Mounts:       []Mount{{GuestPath: "/", FS: fstest.MapFS{"f": {Data: []byte("x")}}}},
+		MaxOpenFiles: limit,
+	})
+	writeMem(t, r, 64, []byte("f"))
+	if err := r.Run(context.Background()); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+
+	if errno := global(r, 1); errno != errnoMfile {
+		t.Errorf("open past the limit failed with errno %d, want EMFILE (%d)", errno, errnoMfile)
+	}
+	// The root of the mount holds one of the slots
+	if n := global(r, 0); n != limit-1 {
+		t.Errorf("guest opened %d files, want %d", n, limit-1)
+	}
+	if n := r.OpenFileCount(); n != limit {
+		t.Errorf("OpenFileCount = %d, want %d", n, limit)
+	}
+}
@@ -0,0 +1,33 @@
+package reactor
+
+// StateObserver is notified as Run processes each go_tick result, for
+// dashboards and debugging tools showing a reactor's activity over time. Each
+// method is called once per tick reporting that state, on the goroutine
+// running the reactor, so implementations should return quickly. See
+// Config.StateObserver.
+type StateObserver interface {
+	// OnReady is called when a tick reports more goroutines runnable.
+	OnReady()
+	// OnTimerWait is called when a tick reports the guest waiting on a
+	// timer due in ms milliseconds, or LoopWaitForever when waiting on the
+	// host.
+	OnTimerWait(ms int32)
+	// OnIdle is called when a tick reports no pending work.
+	OnIdle()
+	// OnExit is called when the guest exits during a tick, with its exit
+	// code.
+	OnExit(code uint32)
+}
+
+// observeResult reports a tick result to Config.StateObserver.
+func (r *Reactor) observeResult(result LoopResult) {
+	o := r.cfg.StateObserver
+	switch {
+	case result == LoopIdle:
+		o.OnIdle()
+	case result == LoopReady:
+		o.OnReady()
+	case result > 0:
+		o.OnTimerWait(int32(result))
+	}
+}
@@ -0,0 +1,51 @@
+package reactor
+
+// RunOutcome describes how a guest finished.
+type RunOutcome struct {
+	// Exited is true if the guest called proc_exit, e.g. via os.Exit.
+	Exited bool
+	// Trapped is true if the guest trapped, e.g. on unreachable or an out of
+	// bounds memory access, instead of exiting. A Go panic is not a trap: the
+	// guest runtime prints it and calls proc_exit(2), so it is reported as an
+	// exit with code 2.
+	Trapped bool
+	// ExitCode is the code passed to proc_exit if Exited, or
+	// Config.TrapExitCode if Trapped.
+	ExitCode uint32
+}
+
+// Outcome returns how the guest finished so far.
+// It is safe to call concurrently with the run loop.
+func (r *Reactor) Outcome() RunOutcome {
+	var out RunOutcome
+	if r.exited.Load() {
+		out.Exited = true
+		out.ExitCode = r.exitCode.Load()
+	} else if r.trapped.Load() {
+		out.Trapped = true
+		out.ExitCode = r.cfg.TrapExitCode
+	}
+	return out
+}
+
+// HasExited reports whether the guest called proc_exit. A trapped guest has
+// not exited; see Outcome. It is safe to call concurrently with the run loop.
+func (r *Reactor) HasExited() bool {
+	return r.exited.Load()
+}
+
+// ExitCode returns the code the guest passed to proc_exit, or zero if it has
+// not exited. It is safe to call concurrently with the run loop.
+func (r *Reactor) ExitCode() uint32 {
+	return r.exitCode.Load()
+}
+
+// IsClosed reports whether the reactor has been closed, by Close or by the
+// harness, e.g. after a host callback panic or an idle timeout, or whether
+// wazero closed its module, as it does when the guest calls proc_exit or a
+// run is interrupted on a runtime closing modules when ctx is done. A guest
+// that trapped is not closed; see Outcome. It is safe to call concurrently
+// with the run loop.
+func (r *Reactor) IsClosed() bool {
+	return r.closed.Load() || r.mod.IsClosed()
+}
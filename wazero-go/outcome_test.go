@@ -0,0 +1,23 @@
+package reactor
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestIsClosedAfterExit(t *testing.T) {
+	m := reactorModule(slices.Concat(i32Const(3), callFunc(0), i32Const(int32(LoopIdle))))
+	m.imports = []testImport{procExit}
+	r := newTestReactor(t, m, nil)
+	if r.IsClosed() {
+		t.Fatal("new reactor is closed")
+	}
+	_ = r.Run(context.Background())
+	if !r.IsClosed() {
+		t.Error("reactor not closed after proc_exit")
+	}
+	if out := r.Outcome(); !out.Exited || out.ExitCode != 3 {
+		t.Errorf("outcome = %+v, want exit code 3", out)
+	}
+}
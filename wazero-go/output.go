@@ -0,0 +1,66 @@
+package reactor
+
+import (
+	"context"
+	"io"
+)
+
+// OutputErrorAction is returned by Config.OnOutputError to decide what happens
+// after writing guest output failed.
+type OutputErrorAction int
+
+const (
+	// OutputErrorFail returns the error to the guest's fd_write, as happens
+	// without OnOutputError. How the guest reacts is up to its own code.
+	OutputErrorFail OutputErrorAction = iota
+	// OutputErrorIgnore discards the output and reports it to the guest as
+	// written, e.g. to keep running after a client disconnected.
+	OutputErrorIgnore
+	// OutputErrorCancel fails the write and cancels the run in progress as if
+	// by Cancel(CancelError).
+	OutputErrorCancel
+	// OutputErrorClose fails the write and closes the reactor.
+	OutputErrorClose
+)
+
+// outputWriter applies Config.OnOutputError to a guest output stream.
+type outputWriter struct {
+	w       io.Writer
+	r       *Reactor
+	onError func(err error) OutputErrorAction
+}
+
+// Write implements io.Writer.
+func (o *outputWriter) Write(p []byte) (int, error) {
+	n, err := o.w.Write(p)
+	if err != nil && o.handle(err) == nil {
+		return len(p), nil
+	}
+	return n, err
+}
+
+// Flush implements Flusher if the underlying writer does.
+func (o *outputWriter) Flush() error {
+	f, ok := o.w.(Flusher)
+	if !ok {
+		return nil
+	}
+	if err := f.Flush(); err != nil {
+		return o.handle(err)
+	}
+	return nil
+}
+
+// handle applies the action chosen by onError, returning nil if the error is
+// to be ignored.
+func (o *outputWriter) handle(err error) error {
+	switch o.onError(err) {
+	case OutputErrorIgnore:
+		return nil
+	case OutputErrorCancel:
+		o.r.Cancel(CancelError)
+	case OutputErrorClose:
+		_ = o.r.Close(context.Background())
+	}
+	return err
+}
@@ -0,0 +1,78 @@
+package reactor
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+// failingWriter accepts n bytes, then fails.
+type failingWriter struct {
+	n       int
+	written []byte
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	m := min(len(p), w.n-len(w.written))
+	w.written = append(w.written, p[:m]...)
+	if m < len(p) {
+		return m, errors.New("writer full")
+	}
+	return m, nil
+}
+
+func TestOnOutputError(t *testing.T) {
+	// go_tick writes "abcd" to stdout, keeping the errno in g1, and is ready
+	// for three ticks
+	tick := slices.Concat(
+		i32Const(1), i32Const(0), i32Const(1), i32Const(16), callFunc(0),
+		[]byte{0x24, 0x01}, // g1 = errno
+		[]byte{0x23, 0x00, 0x41, 0x01, 0x6a, 0x24, 0x00}, // g0++
+		[]byte{0x23, 0x00}, i32Const(3), []byte{0x48, 0x04, 0x7f}, // if g0 < 3 (result i32)
+		i32Const(int32(LoopReady)),
+		[]byte{0x05}, // else
+		i32Const(int32(LoopIdle)),
+		[]byte{0x0b},
+	)
+	tests := []struct {
+		action OutputErrorAction
+		// wantErrno is whether the guest's last write fails.
+		wantErrno bool
+	}{
+		{OutputErrorFail, true},
+		{OutputErrorIgnore, false},
+	}
+	for _, tt := range tests {
+		m := reactorModule(tick)
+		m.imports = []testImport{fdWrite}
+		m.globals = 2
+		w := &failingWriter{n: 6}
+		var errs int
+		r := newTestReactor(t, m, &Config{
+			Stdout: w,
+			OnOutputError: func(error) OutputErrorAction {
+				errs++
+				return tt.action
+			},
+		})
+		writeMem(t, r, 0, iovec(64, 4))
+		writeMem(t, r, 64, []byte("abcd"))
+		if err := r.Run(context.Background()); err != nil {
+			t.Fatalf("action %d: run: %v", tt.action, err)
+		}
+
+		if errs != 2 {
+			t.Errorf("action %d: OnOutputError called %d times, want 2", tt.action, errs)
+		}
+		if string(w.written) != "abcdab" {
+			t.Errorf("action %d: wrote %q, want %q", tt.action, w.written, "abcdab")
+		}
+		if errno := global(r, 1); (errno != 0) != tt.wantErrno {
+			t.Errorf("action %d: guest's last write returned errno %d", tt.action, errno)
+		}
+		if n := global(r, 0); n != 3 {
+			t.Errorf("action %d: guest ticked %d times, want 3", tt.action, n)
+		}
+	}
+}
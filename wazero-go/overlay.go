@@ -0,0 +1,127 @@
+package reactor
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+)
+
+// overlayFS is a read-only union of file systems; see Config.OverlayFS.
+type overlayFS []fs.FS
+
+// Open implements fs.FS.
+func (o overlayFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	return o.open(name)
+}
+
+// open opens name, searching only the layers merged into its parent
+// directory, so a file that shadows a directory also hides everything the
+// lower layers have beneath it.
+func (o overlayFS) open(name string) (fs.File, error) {
+	layers := []fs.FS(o)
+	if name != "." {
+		parent, err := o.open(path.Dir(name))
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+		}
+		if err != nil {
+			return nil, err
+		}
+		parent.Close()
+		dir, ok := parent.(*overlayDir)
+		if !ok {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+		}
+		layers = dir.layers
+	}
+
+	var top fs.File
+	var dirs []fs.FS
+	for _, layer := range layers {
+		f, err := layer.Open(name)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err == nil {
+			var info fs.FileInfo
+			if info, err = f.Stat(); err != nil {
+				f.Close()
+			} else if !info.IsDir() {
+				if top == nil {
+					return f, nil
+				}
+				// A file shadows directories in the layers below it
+				f.Close()
+				break
+			}
+		}
+		if err != nil {
+			if top != nil {
+				top.Close()
+			}
+			return nil, err
+		}
+		if top == nil {
+			top = f
+		} else {
+			f.Close()
+		}
+		dirs = append(dirs, layer)
+	}
+	if top == nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return &overlayDir{File: top, name: name, layers: dirs}, nil
+}
+
+// overlayDir is a directory merged from the layers that have it, topmost
+// first. Stat reports the topmost layer's directory.
+type overlayDir struct {
+	fs.File
+	name    string
+	layers  []fs.FS
+	entries []fs.DirEntry
+	read    bool
+}
+
+// ReadDir implements fs.ReadDirFile.
+func (d *overlayDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	if !d.read {
+		seen := map[string]bool{}
+		for _, layer := range d.layers {
+			entries, err := fs.ReadDir(layer, d.name)
+			if err != nil {
+				return nil, err
+			}
+			for _, e := range entries {
+				if !seen[e.Name()] {
+					seen[e.Name()] = true
+					d.entries = append(d.entries, e)
+				}
+			}
+		}
+		slices.SortFunc(d.entries, func(a, b fs.DirEntry) int {
+			return strings.Compare(a.Name(), b.Name())
+		})
+		d.read = true
+	}
+
+	if n <= 0 {
+		entries := d.entries
+		d.entries = nil
+		return entries, nil
+	}
+	if len(d.entries) == 0 {
+		return nil, io.EOF
+	}
+	n = min(n, len(d.entries))
+	entries := d.entries[:n]
+	d.entries = d.entries[n:]
+	return entries, nil
+}
@@ -0,0 +1,85 @@
+package reactor
+
+import (
+	"io/fs"
+	"slices"
+	"testing"
+	"testing/fstest"
+)
+
+func TestOverlayFS(t *testing.T) {
+	top := fstest.MapFS{
+		"etc/app.conf":  {Data: []byte("top")},
+		"data/new.txt":  {Data: []byte("new")},
+		"cache":         {Data: []byte("not a dir")},
+		"only-top.txt":  {Data: []byte("1")},
+		"lib/top/x.txt": {Data: []byte("x")},
+	}
+	base := fstest.MapFS{
+		"etc/app.conf":   {Data: []byte("base")},
+		"etc/hosts":      {Data: []byte("localhost")},
+		"data/old.txt":   {Data: []byte("old")},
+		"cache/item":     {Data: []byte("hidden")},
+		"only-base.txt":  {Data: []byte("2")},
+		"lib/base/y.txt": {Data: []byte("y")},
+	}
+	o := overlayFS{top, base}
+
+	files := map[string]string{
+		"etc/app.conf":   "top",
+		"etc/hosts":      "localhost",
+		"cache":          "not a dir",
+		"only-top.txt":   "1",
+		"only-base.txt":  "2",
+		"lib/base/y.txt": "y",
+	}
+	for name, want := range files {
+		got, err := fs.ReadFile(o, name)
+		if err != nil {
+			t.Errorf("read %s: %v", name, err)
+		} else if string(got) != want {
+			t.Errorf("read %s = %q, want %q", name, got, want)
+		}
+	}
+	if _, err := o.Open("cache/item"); err == nil {
+		t.Error("file in a lower layer's directory is visible beneath a file shadowing it")
+	}
+
+	dirs := map[string][]string{
+		".":    {"cache", "data", "etc", "lib", "only-base.txt", "only-top.txt"},
+		"data": {"new.txt", "old.txt"},
+		"etc":  {"app.conf", "hosts"},
+		"lib":  {"base", "top"},
+	}
+	for name, want := range dirs {
+		entries, err := fs.ReadDir(o, name)
+		if err != nil {
+			t.Errorf("read dir %s: %v", name, err)
+			continue
+		}
+		var got []string
+		for _, e := range entries {
+			got = append(got, e.Name())
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("read dir %s = %q, want %q", name, got, want)
+		}
+	}
+	if info, err := fs.Stat(o, "cache"); err != nil || info.IsDir() {
+		t.Errorf("stat cache = %v, %v; want the top layer's file", info, err)
+	}
+
+	if _, err := o.Open("../etc"); err == nil {
+		t.Error("opened an invalid path")
+	}
+}
+
+func TestOverlayFSConformance(t *testing.T) {
+	o := overlayFS{
+		fstest.MapFS{"a/one": {Data: []byte("1")}, "b": {Data: []byte("b")}},
+		fstest.MapFS{"a/two": {Data: []byte("2")}, "c/three": {Data: []byte("3")}},
+	}
+	if err := fstest.TestFS(o, "a/one", "a/two", "b", "c/three"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,82 @@
+package reactor
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// maxPanicText bounds the panic text kept by Config.CapturePanic.
+const maxPanicText = 64 << 10
+
+// panicMarkers start the lines the Go runtime prints when a guest crashes.
+// Each includes the preceding newline so only line starts match.
+var panicMarkers = [][]byte{[]byte("\npanic: "), []byte("\nfatal error: ")}
+
+// panicScanner watches a guest's stderr for a panic or fatal error and keeps
+// the text from there on, while passing all output through to w.
+type panicScanner struct {
+	w     io.Writer
+	mu    sync.Mutex
+	tail  []byte // end of the output so far, to match markers split across writes
+	text  []byte
+	found bool
+}
+
+// newPanicScanner returns a scanner writing through to w.
+func newPanicScanner(w io.Writer) *panicScanner {
+	// Start as if after a newline so a marker at the very start matches
+	return &panicScanner{w: w, tail: []byte{'\n'}}
+}
+
+// Write implements io.Writer.
+func (s *panicScanner) Write(p []byte) (int, error) {
+	s.scan(p)
+	return s.w.Write(p)
+}
+
+// Flush implements Flusher if the underlying writer does.
+func (s *panicScanner) Flush() error {
+	if f, ok := s.w.(Flusher); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+func (s *panicScanner) scan(p []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.found {
+		s.keep(p)
+		return
+	}
+	buf := append(s.tail, p...)
+	for _, marker := range panicMarkers {
+		if i := bytes.Index(buf, marker); i >= 0 {
+			s.found = true
+			s.tail = nil
+			s.keep(buf[i+1:])
+			return
+		}
+	}
+	n := len(panicMarkers[1]) - 1
+	s.tail = append(s.tail[:0], buf[max(len(buf)-n, 0):]...)
+}
+
+// keep appends p to the captured text, up to maxPanicText.
+func (s *panicScanner) keep(p []byte) {
+	s.text = append(s.text, p[:min(len(p), maxPanicText-len(s.text))]...)
+}
+
+// PanicText returns what the guest printed to stderr from the first line
+// starting with "panic: " or "fatal error: " onwards, up to 64KiB, or "" if
+// it has not crashed or Config.CapturePanic is unset. It is safe to call
+// concurrently with ticks.
+func (r *Reactor) PanicText() string {
+	if r.panics == nil {
+		return ""
+	}
+	r.panics.mu.Lock()
+	defer r.panics.mu.Unlock()
+	return string(r.panics.text)
+}
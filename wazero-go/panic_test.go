@@ -0,0 +1,40 @@
+package reactor
+
+import (
+	"context"
+	"errors"
+	"io"
+	"slices"
+	"testing"
+
+	"github.com/tetratelabs/wazero/sys"
+)
+
+func TestCapturePanic(t *testing.T) {
+	const (
+		before = "log line\npan"
+		after  = "ic: boom\n\ngoroutine 1 [running]:\nmain.main()\n"
+	)
+	// go_tick writes the two halves to stderr, then exits with code 2 as the
+	// Go runtime does after printing a panic
+	tick := slices.Concat(
+		i32Const(2), i32Const(0), i32Const(1), i32Const(16), callFunc(0), []byte{0x1a},
+		i32Const(2), i32Const(8), i32Const(1), i32Const(16), callFunc(0), []byte{0x1a},
+		i32Const(2), callFunc(1),
+		i32Const(int32(LoopIdle)),
+	)
+	m := reactorModule(tick)
+	m.imports = []testImport{fdWrite, procExit}
+	r := newTestReactor(t, m, &Config{Stderr: io.Discard, CapturePanic: true})
+	writeMem(t, r, 0, slices.Concat(iovec(64, uint32(len(before))), iovec(128, uint32(len(after)))))
+	writeMem(t, r, 64, []byte(before))
+	writeMem(t, r, 128, []byte(after))
+
+	var exitErr *sys.ExitError
+	if err := r.Run(context.Background()); !errors.As(err, &exitErr) || exitErr.ExitCode() != 2 {
+		t.Fatalf("got %v, want exit code 2", err)
+	}
+	if got, want := r.PanicText(), "panic: boom\n\ngoroutine 1 [running]:\nmain.main()\n"; got != want {
+		t.Errorf("PanicText = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,83 @@
+package reactor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// errStageDone closes a stage's input once the stage has finished, so the
+// stage before it sees its writes fail rather than block.
+var errStageDone = errors.New("pipeline stage finished")
+
+// Stage is one reactor in a Pipeline.
+type Stage struct {
+	// Compiled is the module to run. Stages may share a runtime.
+	Compiled *CompiledReactor
+	// Config configures the stage as for Filter; Stdin and Stdout are
+	// replaced by the pipeline.
+	Config *Config
+}
+
+// Pipeline runs stages like a Unix pipeline: input is the first stage's
+// stdin, each stage's stdout is the next stage's stdin, and the last stage's
+// stdout is written to output. All stages run concurrently, each on its own
+// goroutine, and Pipeline returns their outcomes once every stage has
+// finished.
+//
+// Stages are connected by io.Pipe, so a stage writing faster than the next
+// reads blocks until it catches up. When a stage finishes, the next one reads
+// EOF, or the error it failed with; the previous one, if still writing, gets
+// a write error. If ctx is done, all pipes are closed to unblock stages
+// waiting on I/O.
+//
+// As with Filter, guest exits are reported in the outcomes rather than as
+// errors. Other errors are joined, each prefixed by its stage index.
+func Pipeline(ctx context.Context, stages []Stage, input io.Reader, output io.Writer) ([]RunOutcome, error) {
+	outcomes := make([]RunOutcome, len(stages))
+	errs := make([]error, len(stages))
+
+	var pipes []*io.PipeReader
+	var writers []*io.PipeWriter
+	for range max(len(stages)-1, 0) {
+		pr, pw := io.Pipe()
+		pipes = append(pipes, pr)
+		writers = append(writers, pw)
+	}
+	stop := context.AfterFunc(ctx, func() {
+		for i := range pipes {
+			pipes[i].CloseWithError(ctx.Err())
+			writers[i].CloseWithError(ctx.Err())
+		}
+	})
+	defer stop()
+
+	var wg sync.WaitGroup
+	for i, st := range stages {
+		in, out := input, output
+		if i > 0 {
+			in = pipes[i-1]
+		}
+		if i < len(stages)-1 {
+			out = writers[i]
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			outcomes[i], errs[i] = Filter(ctx, st.Compiled, in, out, st.Config)
+			if i < len(stages)-1 {
+				writers[i].CloseWithError(errs[i])
+			}
+			if i > 0 {
+				pipes[i-1].CloseWithError(errStageDone)
+			}
+			if errs[i] != nil {
+				errs[i] = fmt.Errorf("stage %d: %w", i, errs[i])
+			}
+		}()
+	}
+	wg.Wait()
+	return outcomes, errors.Join(errs...)
+}
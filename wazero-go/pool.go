@@ -0,0 +1,183 @@
+package reactor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// DefaultPoolTicks is the number of ticks per unit of weight a Pool member
+// gets each round when NewPool is given zero.
+const DefaultPoolTicks = 16
+
+// Pool runs many reactors on one goroutine, giving each a slice of ticks per
+// round via RunN. Members are weighted: each round a member runs up to its
+// weight times the pool's ticks per weight, so a member of weight 4 gets
+// about four times the ticks of a member of weight 1 while both are busy.
+// Members waiting on a timer or Notify are skipped until they are due, and do
+// not use up their share.
+//
+// A member leaves the pool when it goes idle or fails. Members are not closed
+// by the pool.
+type Pool struct {
+	ticks int
+
+	mu      sync.Mutex
+	members []*poolMember
+	added   chan struct{}
+}
+
+// poolMember is a reactor in a Pool.
+type poolMember struct {
+	r      *Reactor
+	weight int
+	// due is when a member waiting on a timer is next ticked; zero if it is
+	// ready now.
+	due time.Time
+	// waiting is set while the member waits for Notify.
+	waiting bool
+}
+
+// NewPool constructs an empty Pool running up to ticksPerWeight ticks per unit
+// of weight each round. Zero means DefaultPoolTicks.
+func NewPool(ticksPerWeight int) *Pool {
+	if ticksPerWeight <= 0 {
+		ticksPerWeight = DefaultPoolTicks
+	}
+	return &Pool{ticks: ticksPerWeight, added: make(chan struct{}, 1)}
+}
+
+// Add adds r to the pool with weight 1.
+func (p *Pool) Add(r *Reactor) {
+	p.AddWithPriority(r, 1)
+}
+
+// AddWithPriority adds r to the pool with the given weight, at least 1.
+// Higher weights get proportionally more ticks per round, e.g. for
+// latency-sensitive tenants sharing a worker with batch ones. It may be
+// called while Run is running.
+func (p *Pool) AddWithPriority(r *Reactor, weight int) {
+	p.mu.Lock()
+	p.members = append(p.members, &poolMember{r: r, weight: max(weight, 1)})
+	p.mu.Unlock()
+	select {
+	case p.added <- struct{}{}:
+	default:
+	}
+}
+
+// Len returns the number of reactors in the pool.
+func (p *Pool) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.members)
+}
+
+// Run runs the pool's reactors until all have left it or ctx is done. It
+// returns the errors of failed members, joined and each prefixed with its
+// reactor's String, or ctx's error.
+func (p *Pool) Run(ctx context.Context) error {
+	var errs []error
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		p.mu.Lock()
+		members := append([]*poolMember(nil), p.members...)
+		p.mu.Unlock()
+		if len(members) == 0 {
+			return errors.Join(errs...)
+		}
+
+		ran := false
+		for _, m := range members {
+			if !m.ready(time.Now()) {
+				continue
+			}
+			ran = true
+			res, err := m.r.RunN(ctx, m.weight*p.ticks)
+			switch {
+			case err != nil:
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				errs = append(errs, fmt.Errorf("%s: %w", m.r, err))
+				p.remove(m)
+			case res.Yielded || res.Result == LoopReady:
+				m.due = time.Time{}
+			case res.Result == LoopIdle:
+				p.remove(m)
+			case res.Result == LoopWaitForever:
+				m.waiting = true
+			default:
+				m.due = time.Now().Add(time.Duration(res.Result) * time.Millisecond)
+			}
+		}
+		if !ran {
+			if err := p.wait(ctx, members); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// ready reports whether the member should be ticked this round.
+func (m *poolMember) ready(now time.Time) bool {
+	if m.waiting {
+		select {
+		case <-m.r.wake:
+			m.waiting = false
+		default:
+			return false
+		}
+	}
+	return !now.Before(m.due)
+}
+
+// remove removes a member from the pool.
+func (p *Pool) remove(m *poolMember) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i, x := range p.members {
+		if x == m {
+			p.members = append(p.members[:i], p.members[i+1:]...)
+			return
+		}
+	}
+}
+
+// wait blocks until a member is due, one waiting for Notify is notified, a
+// member is added, or ctx is done.
+func (p *Pool) wait(ctx context.Context, members []*poolMember) error {
+	var due time.Time
+	cases := []reflect.SelectCase{
+		{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())},
+		{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(p.added)},
+	}
+	var notified []*poolMember
+	for _, m := range members {
+		if m.waiting {
+			cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(m.r.wake)})
+			notified = append(notified, m)
+		} else if due.IsZero() || m.due.Before(due) {
+			due = m.due
+		}
+	}
+	if !due.IsZero() {
+		timer := time.NewTimer(time.Until(due))
+		defer timer.Stop()
+		cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(timer.C)})
+	}
+
+	chosen, _, _ := reflect.Select(cases)
+	switch {
+	case chosen == 0:
+		return ctx.Err()
+	case chosen >= 2 && chosen-2 < len(notified):
+		notified[chosen-2].waiting = false
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package reactor
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+)
+
+// readyFor returns a go_tick body counting ticks in g0 that reports LoopReady
+// for the first n-1 ticks and LoopIdle from the nth.
+func readyFor(n int32) []byte {
+	return slices.Concat(
+		[]byte{0x23, 0x00, 0x41, 0x01, 0x6a, 0x24, 0x00}, // g0++
+		[]byte{0x23, 0x00}, i32Const(n), []byte{0x48, 0x04, 0x7f}, // if g0 < n (result i32)
+		i32Const(int32(LoopReady)),
+		[]byte{0x05}, // else
+		i32Const(int32(LoopIdle)),
+		[]byte{0x0b},
+	)
+}
+
+func TestPoolWeights(t *testing.T) {
+	const ticksPerWeight = 2
+	// light has weight 1 and goes idle after 10 rounds of 2 ticks; heavy has
+	// weight 3, so should have run 3 times as many by then
+	lightModule, heavyModule := reactorModule(readyFor(21)), reactorModule(readyFor(1000))
+	lightModule.globals, heavyModule.globals = 1, 1
+	var heavy *Reactor
+	var heavyTicks uint64
+	light := newTestReactor(t, lightModule, &Config{
+		OnFirstIdle: func(time.Duration) { heavyTicks = heavy.Ticks() },
+	})
+	heavy = newTestReactor(t, heavyModule, nil)
+
+	p := NewPool(ticksPerWeight)
+	p.Add(light)
+	p.AddWithPriority(heavy, 3)
+	if err := p.Run(context.Background()); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if n := light.Ticks(); n != 21 {
+		t.Errorf("light ticked %d times, want 21", n)
+	}
+	if heavyTicks != 60 {
+		t.Errorf("heavy ticked %d times while light ran 20 ready ticks, want 60", heavyTicks)
+	}
+	if n := heavy.Ticks(); n != 1000 {
+		t.Errorf("heavy ticked %d times, want 1000", n)
+	}
+}
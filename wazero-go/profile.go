@@ -0,0 +1,52 @@
+package reactor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/tetratelabs/wazero/api"
+)
+
+// ProfileMountPath is where Config.ProfileDir is mounted in the guest.
+const ProfileMountPath = "/.reactor/pprof"
+
+// HeapProfileName is the file name, relative to ProfileMountPath, that the
+// guest writes its heap profile to.
+const HeapProfileName = "heap.pprof"
+
+// HeapProfile asks the guest to write a pprof heap profile and returns it.
+//
+// The guest must export go_write_heap_profile() i32, which writes the output
+// of runtime/pprof.WriteHeapProfile to ProfileMountPath/HeapProfileName and
+// returns zero on success. Config.ProfileDir must be set so that the host can
+// read the file back; it is removed after reading.
+//
+// Returns ErrUnsupported if the guest does not export go_write_heap_profile.
+func (r *Reactor) HeapProfile(ctx context.Context) ([]byte, error) {
+	fn, err := r.optionalExport("go_write_heap_profile")
+	if err != nil {
+		return nil, err
+	}
+	if r.cfg.ProfileDir == "" {
+		return nil, errors.New("heap profile requires Config.ProfileDir")
+	}
+
+	results, err := r.call(ctx, fn)
+	if err != nil {
+		return nil, fmt.Errorf("go_write_heap_profile: %w", err)
+	}
+	if code := api.DecodeI32(results[0]); code != 0 {
+		return nil, fmt.Errorf("go_write_heap_profile failed with code %d", code)
+	}
+
+	path := filepath.Join(r.cfg.ProfileDir, HeapProfileName)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read heap profile: %w", err)
+	}
+	_ = os.Remove(path)
+	return data, nil
+}
@@ -0,0 +1,75 @@
+package reactor
+
+import (
+	"bytes"
+	"io"
+	mathrand "math/rand"
+	"sync"
+)
+
+// hostState is the host-side state of an instance that its WASI hooks report
+// to, created before the module is instantiated.
+type hostState struct {
+	files  *openFiles
+	random *randomRecorder
+}
+
+// newHostState returns the host state for a new instance.
+func (cfg *Config) newHostState() *hostState {
+	h := &hostState{files: &openFiles{max: int64(cfg.MaxOpenFiles)}}
+	if cfg.RecordRandom {
+		h.random = &randomRecorder{}
+	}
+	return h
+}
+
+// randSource returns the reader random_get should use, or nil for wazero's
+// default.
+func (cfg *Config) randSource(caps Capabilities, host *hostState) io.Reader {
+	var src io.Reader
+	switch {
+	case cfg.ReplayRandom != nil:
+		src = bytes.NewReader(cfg.ReplayRandom)
+	case !caps.AllowRandom:
+		src = zeroReader{}
+	}
+	if host.random == nil {
+		return src
+	}
+	if src == nil {
+		// Same as wazero's default, which cannot be wrapped directly
+		src = mathrand.New(mathrand.NewSource(42))
+	}
+	host.random.src = src
+	return host.random
+}
+
+// randomRecorder records what random_get reads from src.
+type randomRecorder struct {
+	src io.Reader
+	mu  sync.Mutex
+	buf []byte
+}
+
+// Read implements io.Reader.
+func (r *randomRecorder) Read(p []byte) (int, error) {
+	n, err := r.src.Read(p)
+	r.mu.Lock()
+	r.buf = append(r.buf, p[:n]...)
+	r.mu.Unlock()
+	return n, err
+}
+
+// ConsumedRandom returns a copy of the bytes the guest has read from
+// random_get so far, in order, if Config.RecordRandom is set; otherwise nil.
+// Pass it as Config.ReplayRandom to replay the run. See Config.RecordRandom
+// for why the result must be handled as a secret.
+func (r *Reactor) ConsumedRandom() []byte {
+	rec := r.host.random
+	if rec == nil {
+		return nil
+	}
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	return bytes.Clone(rec.buf)
+}
@@ -18,6 +18,15 @@
 //	if err := reactor.Run(ctx); err != nil {
 //	    log.Fatal(err)
 //	}
+//
+// # Contexts
+//
+// Every call into the guest uses the context passed by the caller of the
+// method that triggered it; no background context is substituted. Host
+// functions (see HostModule) therefore observe the caller's context, including
+// during _initialize, which runs with the context passed to NewReactor. This
+// lets host imports read tracing or auth values from the context at any point
+// in the guest's lifecycle.
 package reactor
 
 import (
@@ -25,12 +34,21 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
+	"log/slog"
+	"maps"
+	"math"
 	"os"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/tetratelabs/wazero"
 	"github.com/tetratelabs/wazero/api"
 	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"github.com/tetratelabs/wazero/sys"
 )
 
 // LoopResult represents the return value from go_tick.
@@ -42,12 +60,105 @@ const (
 	// LoopReady indicates more goroutines are runnable.
 	LoopReady LoopResult = 0
 	// Values > 0 indicate milliseconds until next timer.
+
+	// LoopWaitForever is the largest positive LoopResult. A guest whose next
+	// timer is too far away to fit in an int32 number of milliseconds (about
+	// 24.8 days) may clamp to it, so hosts do not start a literal timer for it
+	// and instead wait until Notify is called.
+	LoopWaitForever LoopResult = math.MaxInt32
 )
 
+// wasmPageSize is the size of a WebAssembly linear memory page.
+const wasmPageSize = 65536
+
+// ErrIdleTimeout is returned by Serve when the reactor was closed after
+// staying idle for longer than Config.IdleTimeout.
+var ErrIdleTimeout = errors.New("reactor closed after idle timeout")
+
+// ErrRunTimeout is returned by Run when Config.MaxRunDuration elapses before
+// the guest finishes.
+var ErrRunTimeout = errors.New("reactor run exceeded maximum duration")
+
+// ErrInitTimeout is returned by NewReactor and Instantiate when _initialize
+// runs for longer than Config.InitTimeout.
+var ErrInitTimeout = errors.New("reactor _initialize exceeded timeout")
+
+// ErrTickLimitExceeded is returned by Run when the guest has been ticked
+// Config.MaxTicks times without finishing.
+var ErrTickLimitExceeded = errors.New("reactor run exceeded maximum ticks")
+
+// ErrReactorTerminated is returned when calling into a guest that has already
+// exited, trapped, or been closed, wrapping the error that ended it, if any,
+// rather than wazero's less helpful error for a closed module.
+var ErrReactorTerminated = errors.New("reactor terminated")
+
+// ErrUnsupported is returned when the guest does not export a function needed
+// by an optional feature.
+var ErrUnsupported = errors.New("operation not supported by guest module")
+
+// CompileError is returned by Compile and NewReactor when the wasm fails to compile, e.g.
+// because it is malformed or uses unsupported features. Retrying the same
+// bytes will not succeed.
+type CompileError struct {
+	Err error
+}
+
+// Error implements error.
+func (e *CompileError) Error() string {
+	return "compile module: " + e.Err.Error()
+}
+
+// Unwrap returns the underlying wazero error.
+func (e *CompileError) Unwrap() error {
+	return e.Err
+}
+
+// InstantiateError is returned by NewReactor when a compiled module fails to
+// instantiate, e.g. due to unsatisfied imports or a trap in a start function.
+// It may succeed with a different Config or runtime.
+type InstantiateError struct {
+	Err error
+}
+
+// Error implements error.
+func (e *InstantiateError) Error() string {
+	return "instantiate module: " + e.Err.Error()
+}
+
+// Unwrap returns the underlying wazero error.
+func (e *InstantiateError) Unwrap() error {
+	return e.Err
+}
+
 // Config configures a Reactor instance.
 type Config struct {
+	// Name identifies the reactor in logs and String output.
+	Name string
+	// Metadata is arbitrary data attached to the reactor, such as a tenant or
+	// request ID, for hooks that label their output. It is copied when the
+	// reactor is created; see Reactor.Metadata.
+	Metadata map[string]any
 	// Stdin is the reader for stdin. Defaults to os.Stdin.
 	Stdin io.Reader
+	// StdinFunc, if set, supplies stdin on demand in place of Stdin: it is
+	// called each time the guest reads stdin, with the guest's buffer, and
+	// follows io.Reader's contract, returning 0, io.EOF once input is
+	// exhausted. Input is thus generated or fetched only as the guest asks
+	// for it. It runs inside the tick, so a slow call delays the guest.
+	StdinFunc func(p []byte) (int, error)
+	// StdinBufferLimit, if positive, replaces Stdin with a pipe the host
+	// writes to through Reactor.StdinWriter, holding at most this many
+	// unread bytes. Writes block while it is full, providing backpressure
+	// against a guest that consumes input slowly.
+	StdinBufferLimit int
+	// RecordFD, if set, is the guest file descriptor whose output is decoded
+	// into discrete records, delivered by Reactor.Records, instead of being
+	// written to Stdout or Stderr. Each record is framed as a little-endian
+	// uint32 length followed by that many bytes, and may be written in any
+	// number of pieces, across ticks. WASI gives the guest no other fds
+	// without a filesystem, so it must be 1 (stdout) or 2 (stderr); the
+	// other stream keeps carrying text, e.g. logs.
+	RecordFD int
 	// Stdout is the writer for stdout. Defaults to os.Stdout.
 	Stdout io.Writer
 	// Stderr is the writer for stderr. Defaults to os.Stderr.
@@ -57,80 +168,642 @@ type Config struct {
 	// Env are environment variables in "KEY=VALUE" format.
 	Env []string
 	// FS is the filesystem to mount. If nil, no filesystem is mounted.
+	//
+	// wazero numbers the guest's file descriptors from 3 upwards for the
+	// directories preopened here, followed by the files the guest opens, and
+	// offers no way to place a host io.Reader or io.Writer at a chosen fd, so
+	// there is no equivalent of os/exec's ExtraFiles. Guests needing I/O
+	// channels beyond stdio can open files in a directory mounted here, or
+	// exchange data through functions provided by HostModules.
 	FS wazero.FSConfig
+	// WorkDir is the guest's initial working directory, an absolute guest
+	// path such as "/data". Relative paths the guest opens resolve against
+	// it, so it should lie within FS or one of Mounts; the guest path of a
+	// mount, not its host directory. Defaults to "/".
+	//
+	// WASI has no working directory, so it is passed as the PWD environment
+	// variable, which Go guests read at startup. It overrides any PWD in Env
+	// and is set even when Capabilities deny Env.
+	WorkDir string
+	// GoroutinesPerTick is the maximum number of goroutines the guest
+	// scheduler runs per go_tick. Zero leaves the guest runtime default.
+	//
+	// Lower values return control to the host more often, improving fairness
+	// and latency for the host at the cost of more boundary crossings. Higher
+	// values reduce that overhead and improve throughput, but a busy guest
+	// holds the host for longer per tick. Requires the guest to export
+	// go_set_tick_budget; NewReactor fails with ErrUnsupported otherwise.
+	GoroutinesPerTick int
+	// ClockResolution backs the guest's monotonic clock with the host's (or
+	// Clock, if set) and reports this value from clock_res_get. Readings are
+	// truncated to a multiple of the resolution. If zero and Clock is nil,
+	// wazero's default clock is used.
+	//
+	// The host clock itself may be coarser than requested: Windows commonly
+	// ticks at 0.5-15ms, and some virtualized hosts at 1ms or worse. Setting a
+	// finer resolution than the platform provides has no effect on accuracy.
+	ClockResolution time.Duration
+	// WaitStrategy decides how Run waits for pending guest timers.
+	// Defaults to TimerWait. SpinWait and HybridWait reduce wakeup latency for
+	// short timers at the cost of CPU.
+	WaitStrategy WaitStrategy
+	// MinTimerWait, if positive, lengthens waits for guest timers to at
+	// least this long, so a guest returning many tiny delays (1ms, 1ms, ...)
+	// wakes the host less often. Timers then fire up to MinTimerWait late,
+	// trading latency for fewer wakeups and less CPU, e.g. for background
+	// reactors on battery-powered or serverless hosts. Notify still ends a
+	// wait early.
+	MinTimerWait time.Duration
+	// MaxTimerWait, if positive, caps waits for guest timers, ticking the
+	// guest at least this often while it waits on a timer; it then reports
+	// the time remaining. It does not apply to LoopWaitForever.
+	//
+	// Neither setting applies when Config.Clock is advanced virtually.
+	MaxTimerWait time.Duration
+	// HostModules are host modules instantiated into the runtime before the
+	// guest, satisfying its non-WASI imports. Modules already instantiated in
+	// the runtime, e.g. by an earlier reactor, are reused as-is, provided
+	// they export every function listed; otherwise creating the reactor
+	// fails. The built-in imports enabled by HostSeq and Secrets also live in
+	// env, so a custom env module must be declared, with those built-ins
+	// enabled, by the first reactor on a runtime that needs any of them. A
+	// panic in a host function closes the reactor and is returned from
+	// LoopOnce as a HostCallbackError.
+	HostModules []HostModule
+	// HostModuleSet, if set, is a set of host modules shared with other
+	// reactors, instantiated once per runtime before HostModules.
+	HostModuleSet *HostModuleSet
+	// Registry, if set, tracks the reactor until it is closed.
+	Registry *Registry
+	// Budget, if set, charges the reactor's memory and ticks to a budget
+	// shared with other reactors, and stops ticking it once the budget is
+	// exceeded.
+	Budget *Budget
+	// IdleTimeout, if set, closes the reactor when it stays idle in Serve for
+	// longer than this duration without a Notify. Serve then returns
+	// ErrIdleTimeout.
+	IdleTimeout time.Duration
+	// OnIdleTimeout is called before the reactor is closed by IdleTimeout.
+	OnIdleTimeout func()
+	// SchedulerSeed, if non-zero, is passed to the guest's go_set_sched_seed
+	// export after _initialize so that goroutine ordering is reproducible
+	// between runs. This is intended for testing race-prone guest code only.
+	//
+	// If the guest does not export go_set_sched_seed the seed is ignored,
+	// unless StrictSchedulerSeed is set, in which case NewReactor fails with
+	// ErrUnsupported.
+	SchedulerSeed uint64
+	// StrictSchedulerSeed makes an unsupported SchedulerSeed an error.
+	StrictSchedulerSeed bool
+	// InstantiateRetries is how many more times instantiating the module is
+	// attempted after a transient failure, currently only running out of
+	// memory (ENOMEM) while mapping it, which can happen when spinning up
+	// many reactors at once. Other failures, such as unsatisfied imports,
+	// are returned at once. Each retry is logged, and the last error is
+	// returned if all attempts fail.
+	InstantiateRetries int
+	// InstantiateBackoff is the wait before the first retry, doubling for
+	// each further retry. Defaults to DefaultInstantiateBackoff.
+	InstantiateBackoff time.Duration
+	// InitTimeout, if set, bounds the guest's _initialize. If it takes longer,
+	// the module is closed and ErrInitTimeout is returned. As with
+	// MaxRunDuration, a hung _initialize is only interrupted if the runtime
+	// was created with wazero.RuntimeConfig.WithCloseOnContextDone(true);
+	// otherwise the timeout is reported once _initialize returns.
+	InitTimeout time.Duration
+	// YieldEvery, if positive, makes Run, RunWithCallback, and Serve call
+	// runtime.Gosched after every YieldEvery consecutive ready ticks, so a
+	// busy guest does not starve other goroutines of the host. Zero never
+	// yields.
+	YieldEvery int
+	// MaxTicks, if positive, is a hard cap on the number of go_tick calls in
+	// each Run, RunWithCallback, or Serve call, after which it returns
+	// ErrTickLimitExceeded, to abort untrusted guests that compute without
+	// end. Unlike a time limit it does not depend on host speed.
+	MaxTicks uint64
+	// MaxRunDuration bounds the wall-clock time of each Run, RunWithCallback,
+	// or Serve call. When exceeded they return ErrRunTimeout.
+	//
+	// A tick in progress is only interrupted if the runtime was created with
+	// wazero.RuntimeConfig.WithCloseOnContextDone(true), in which case the
+	// module is closed; otherwise the deadline is checked between ticks.
+	MaxRunDuration time.Duration
+	// OnMemoryGrow is called when the guest's linear memory has grown, with
+	// the size before and after in 64KiB pages.
+	//
+	// Growth is detected by polling the memory size after _initialize and
+	// after each go_tick, so several memory.grow calls within one tick are
+	// reported as a single change.
+	OnMemoryGrow func(oldPages, newPages uint32)
+	// FlushAfterTick flushes Stdout and Stderr after every go_tick in Run,
+	// RunWithCallback, and Serve, so output from guests that buffer writes
+	// appears promptly. Only writers implementing Flusher, such as
+	// *bufio.Writer, are flushed; others are left alone.
+	FlushAfterTick bool
+	// ManualStart stops Run, RunWithCallback, and Serve from calling
+	// StartMain; the caller is responsible for queuing main, e.g. after
+	// gating on readiness, or may never start it and only drive exports.
+	//
+	// StartMain is idempotent, so without ManualStart a Run after a manual
+	// StartMain does not start main twice; ManualStart is only needed when
+	// Run must not start main itself.
+	ManualStart bool
+	// AutoStart makes NewReactor and Instantiate call StartMain once the
+	// reactor is set up, so LoopOnce can be used straight away. Combined with
+	// calling LoopOnce directly, this is the lowest-ceremony way to drive a
+	// reactor. StartMain is idempotent, so a later Run does not start main
+	// again.
+	AutoStart bool
+	// ProfileDir is a host directory mounted read-write into the guest at
+	// ProfileMountPath, through which the guest hands profiles to the host.
+	// Required by HeapProfile.
+	ProfileDir string
+	// Logger receives diagnostics from the harness. If nil, nothing is logged.
+	Logger *slog.Logger
+	// MaxProcs is passed to the guest's go_set_maxprocs export, if present,
+	// after _initialize. Zero leaves the guest default.
+	//
+	// wasip1 is single-threaded and the host drives the scheduler from one
+	// goroutine, so a reactor effectively runs with GOMAXPROCS=1 whatever the
+	// guest reports. If the guest exports go_gomaxprocs and reports more than
+	// one, a warning is logged, as guest code tuned for parallelism will not
+	// get it.
+	MaxProcs int
+	// FinalTickOnCancel runs one more go_tick after a run is cancelled via
+	// Cancel, so the guest can observe the cancel reason. The final tick is
+	// skipped if the module was closed by the cancellation.
+	FinalTickOnCancel bool
+	// Timings, if set, receives the duration of each phase of creating the
+	// reactor. It is written before NewReactor or Instantiate returns.
+	Timings *Timings
+	// HostSeq links a built-in env.host_seq() i64 import returning a
+	// process-wide, monotonically increasing counter starting at 1, which
+	// guests can use to order events against the host or mint host-unique
+	// IDs. The import is only linked when enabled, or when another reactor on
+	// the same runtime enabled it or Secrets first:
+	//
+	//	//go:wasmimport env host_seq
+	//	func hostSeq() uint64
+	HostSeq bool
+	// Clock, if set, supplies the guest's wall and monotonic clocks.
+	// If it also has an Advance(time.Duration) method, as FastForwardClock
+	// does, the run loop advances it by each pending guest timer instead of
+	// waiting for the timer in real time.
+	Clock Clock
+	// OnFirstIdle is called once, the first time go_tick reports idle, with
+	// the time elapsed since StartMain (or since creation if main was never
+	// started). This measures how long the guest's startup burst takes to
+	// settle. Later idle transitions do not call it again.
+	OnFirstIdle func(elapsed time.Duration)
+	// WarnOnImmediateIdle logs a warning if the very first go_tick after
+	// StartMain reports idle. A real main almost always runs for at least
+	// one tick first, so this usually means a mis-built guest whose main was
+	// never linked or was optimized away, which would otherwise look like a
+	// program that finished instantly and successfully.
+	WarnOnImmediateIdle bool
+	// StateObserver, if set, is told of each state Run finds the guest in:
+	// ready, waiting on a timer, idle, or exited. It brings together what
+	// separate hooks such as OnFirstIdle report piecemeal.
+	StateObserver StateObserver
+	// Capabilities restricts the WASI capabilities available to the guest.
+	// If nil, all are allowed. See Capabilities for what the guest observes
+	// when one is denied.
+	Capabilities *Capabilities
+	// Store, if set, makes the reactor resumable across host restarts.
+	// Instantiate restores the snapshot returned by Store.Load, if any, in
+	// place of calling _initialize, and Run, RunWithCallback, and Serve save
+	// a new snapshot between ticks every SnapshotInterval and when the guest
+	// goes idle. Save errors stop the run. Snapshots record the hash of the
+	// wasm they were taken from, so a Store holding another module's
+	// snapshot fails Instantiate with ErrBadSnapshot. See Reactor.Snapshot for
+	// what a snapshot does and does not cover.
+	Store Store
+	// SnapshotInterval is the minimum time between snapshots saved to Store.
+	// Defaults to DefaultSnapshotInterval.
+	SnapshotInterval time.Duration
+	// OverlayFS, if set, is mounted read-only at the guest's root as a union
+	// of the given file systems, e.g. a base image shared by many reactors
+	// plus a small per-instance layer, without copying the base.
+	//
+	// Layers are searched in order and the first one containing a path wins:
+	// a file shadows the same path in every later layer, including a
+	// directory of that name and everything in it. Directories present in
+	// several layers are merged, listing each name once as seen in the first
+	// layer that has it, down to the first layer where the path is not a
+	// directory. There are no whiteouts, so a layer cannot hide a path without
+	// replacing it.
+	// Files opened through it count toward MaxOpenFiles.
+	OverlayFS []fs.FS
+	// Mounts are additional file systems exposed to the guest, alongside FS.
+	Mounts []Mount
+	// CapturePanic scans the guest's stderr for the text the Go runtime
+	// prints when it panics or hits a fatal error, so a crash can be
+	// correlated with its cause; see Reactor.PanicText. Scanning happens
+	// before output reaches Stderr, so it works even if Stderr is io.Discard.
+	CapturePanic bool
+	// CaptureOutput keeps a copy of what the guest writes to stdout and
+	// stderr, retrievable with DrainStdout and DrainStderr. Output is still
+	// written to Stdout and Stderr as usual.
+	CaptureOutput bool
+	// CaptureLimit, if positive, bounds each capture buffer to its most
+	// recent CaptureLimit bytes, dropping older output that was not drained.
+	CaptureLimit int
+	// NormalizeLineEndings converts CRLF line endings in guest output to LF,
+	// so output captured on one OS compares equal to output captured on
+	// another, e.g. in golden tests. It applies to the streams selected by
+	// the mode: typically NormalizeCaptured, which leaves the bytes passed
+	// through to Stdout and Stderr untouched. A CR ending one write is held
+	// back until the next write shows whether it starts a CRLF.
+	NormalizeLineEndings LineEndingMode
+	// OnOutputError, if set, is called when writing to Stdout or Stderr
+	// fails, e.g. with a broken pipe after a client disconnected, and decides
+	// what happens next. Without it the error is returned to the guest's
+	// fd_write and the run carries on.
+	OnOutputError func(err error) OutputErrorAction
+	// RecordRandom records every byte the guest reads from random_get, for
+	// auditing or replaying the run; see Reactor.ConsumedRandom.
+	//
+	// The recording is as sensitive as the guest's secrets: keys, nonces, and
+	// tokens the guest generates can be recomputed from it, so store it as
+	// carefully as those secrets, and never record production guests whose
+	// randomness protects anything.
+	RecordRandom bool
+	// ReplayRandom, if set, is fed to random_get instead of any other source,
+	// typically a previous run's ConsumedRandom. Together with recorded stdin
+	// this replays a guest run deterministically. Once it is used up,
+	// random_get fails in the guest. It takes precedence over
+	// Capabilities.AllowRandom.
+	ReplayRandom []byte
+	// TrapExitCode is the exit code reported in RunOutcome when the guest
+	// traps, so CLI wrappers can map traps to a fixed code such as 70.
+	// wazero reports no exit code for traps, so the default is zero;
+	// RunOutcome.Trapped tells it apart from a clean exit.
+	TrapExitCode uint32
+	// WASITrace, if set, is called after every WASI function the guest calls,
+	// with its name, arguments, and result, to diagnose what a guest does at
+	// the WASI boundary, e.g. unexpected clock or fd usage. It is called
+	// synchronously inside the tick and slows every WASI call, so enable it
+	// only while debugging.
+	//
+	// Calls are observed by a listener installed when the first reactor on a
+	// runtime instantiates WASI; if the caller instantiated WASI on the
+	// runtime itself, nothing is traced.
+	WASITrace func(call WASICall)
+	// InitialMemoryPages, if larger than the module's initial memory, grows
+	// the guest's linear memory to this many 64KiB pages before _initialize,
+	// so a guest that allocates heavily at startup does not grow its memory
+	// repeatedly. The Go runtime uses memory it finds already present before
+	// growing it.
+	//
+	// The trade-off is footprint: the pages are committed up front and never
+	// released, even if the guest would have stayed smaller. It fails
+	// instantiation if it exceeds the module's maximum memory.
+	InitialMemoryPages uint32
+	// TickWatchdog, if set, logs a warning when a single go_tick runs for
+	// longer than this, e.g. because a guest goroutine is stuck in a loop, so
+	// pathological ticks are surfaced rather than hanging silently.
+	TickWatchdog time.Duration
+	// TickWatchdogAction is what TickWatchdog does besides logging. With
+	// WatchdogCancel the tick's context is cancelled, which only interrupts
+	// the guest if the runtime was created with
+	// wazero.RuntimeConfig.WithCloseOnContextDone(true); the module is then
+	// closed, so the reactor cannot be used again.
+	TickWatchdogAction WatchdogAction
+	// StopOnStdinEOF makes Run, RunWithCallback, and Serve return nil,
+	// rather than park or wait for Notify, once the guest goes idle or waits
+	// without a timer after reading EOF from Stdin. This suits filter-style
+	// guests that process stdin until EOF and are then done, even if they
+	// keep goroutines waiting on input that will never come.
+	StopOnStdinEOF bool
+	// CountIO counts the bytes the guest reads from Stdin and writes to
+	// Stdout and Stderr, for Report.
+	CountIO bool
+	// MaxOutputPerTick, if positive, makes RunN yield after a tick in which
+	// the guest wrote more than this many bytes to stdout and stderr, even
+	// if it is still ready, so a guest flooding its output cannot monopolize
+	// a worker shared with other reactors. The tick itself is not cut short.
+	MaxOutputPerTick int64
+	// Metrics, if set, receives metrics the guest emits through the
+	// reactor_metrics host module, so guest code can contribute application
+	// metrics without a side channel:
+	//
+	//	//go:wasmimport reactor_metrics inc_counter
+	//	func incCounter(name unsafe.Pointer, nameLen uint32, delta int64)
+	//
+	//	//go:wasmimport reactor_metrics set_gauge
+	//	func setGauge(name unsafe.Pointer, nameLen uint32, value float64)
+	//
+	// Names are UTF-8 bytes in guest memory; they are interned per reactor,
+	// so repeated names do not allocate. Calls with an out of range name are
+	// ignored. If the Collector implements WakeLatencyObserver, the reactor
+	// also reports its wake latency.
+	Metrics Collector
+	// Secrets are delivered to the guest on request through the
+	// env.go_get_secret import rather than the environment, so they are not
+	// visible to everything that can read the environment block:
+	//
+	//	//go:wasmimport env go_get_secret
+	//	func getSecret(name unsafe.Pointer, nameLen uint32, buf unsafe.Pointer, bufLen uint32) int32
+	//
+	// go_get_secret copies the named secret into buf and returns its length.
+	// If bufLen is too small it returns the length without copying, so the
+	// guest can retry with a larger buffer. It returns -1 for an unknown name
+	// or a secret already delivered: each secret is handed out once per
+	// reactor, after which the reactor zeroes its bytes.
+	//
+	// Each reactor copies the secrets when it is created and zeroes only its
+	// own copy, so the map and slices here are left untouched and a Config
+	// may be reused, e.g. by Clone, with each reactor getting every secret
+	// once. Zeroing does not reach copies made elsewhere, such as this map,
+	// the guest's, or the Go runtime's, so this is best-effort hygiene
+	// rather than a guarantee; clear the map once reactors are created if
+	// it should not outlive them.
+	// Like HostSeq, the import lives in the shared env host module; a guest
+	// importing it without Secrets set gets -1.
+	Secrets map[string][]byte
+	// OnProcExit, if set, is called when the guest calls proc_exit, with the
+	// exit code and the time of the call, for audit logging. It is observed
+	// at the WASI boundary, inside the tick and before the exit unwinds the
+	// guest, so it runs before the exit reaches the host: before LoopOnce or
+	// Run return, before Outcome and HasExited report it, and before buffered
+	// output is flushed. The same code is reported as RunOutcome.ExitCode.
+	// Like WASITrace, it relies on the harness instantiating WASI.
+	OnProcExit func(code uint32, at time.Time)
+	// MaxClockCalls, if positive, limits how many times the guest may read
+	// the clock through WASI clock_time_get and clock_res_get over its
+	// lifetime, to stop an untrusted guest spinning on the clock, e.g. for a
+	// timing side channel. The call that exceeds it fails with
+	// ErrClockLimitExceeded and the reactor is closed. The count is reported
+	// by Reactor.ClockCallCount. Like WASITrace, it relies on the harness
+	// instantiating WASI.
+	MaxClockCalls uint64
+	// AllowedExports, if set, restricts which functions the guest module may
+	// export: instantiation fails with ErrDisallowedExport if it exports
+	// any function other than the reactor ABI (_initialize, go_start_main,
+	// go_tick) and those listed. Optional exports the harness uses, such as
+	// go_wants_tick or go_alloc, must be listed to be allowed. Exported
+	// memories and globals are not checked.
+	AllowedExports []string
+	// InstantiateLimiter, if set, bounds how many reactors sharing it may be
+	// instantiating and running _initialize, or restoring a snapshot, at
+	// once. Others wait their turn, or give up with ctx's error if it is done
+	// while they wait, so a burst of cold starts is spread out rather than
+	// spiking memory and CPU.
+	InstantiateLimiter *Limiter
+	// MaxOpenFiles, if positive, limits how many files and directories the
+	// guest may have open at once through Mounts, to stop untrusted guests
+	// from exhausting host file descriptors. Further opens fail in the guest
+	// with EMFILE until a file is closed. The root of each mount counts once
+	// the guest first uses it. Files opened through FS cannot be counted, so
+	// setting both FS and MaxOpenFiles is an error.
+	MaxOpenFiles int
+}
+
+// Timings records how long each phase of creating a reactor took, to identify
+// what dominates cold start.
+type Timings struct {
+	// Compile is the time spent compiling the wasm. Only set by NewReactor;
+	// it is zero when instantiating an already compiled module.
+	Compile time.Duration
+	// Instantiate is the time spent instantiating host modules and the guest.
+	Instantiate time.Duration
+	// Initialize is the time spent in the guest's _initialize.
+	Initialize time.Duration
+	// Warm is the time spent in Reactor.Warm, if it was called.
+	Warm time.Duration
+}
+
+// Flusher is implemented by output writers that buffer data.
+// See Config.FlushAfterTick.
+type Flusher interface {
+	Flush() error
 }
 
 // Reactor wraps a Go WASI reactor module and provides methods to drive it.
 type Reactor struct {
-	runtime wazero.Runtime
-	mod     api.Module
+	runtime  wazero.Runtime
+	compiled *CompiledReactor
+	mod      api.Module
+
+	initialize  api.Function
+	goStartMain api.Function
+	goTick      api.Function
+	goWantsTick api.Function
+	cfg         Config
+	stdout      io.Writer
+	stderr      io.Writer
+	wait        WaitStrategy
+	wake        chan struct{}
+
+	stdoutCapture *captureBuffer
+	stderrCapture *captureBuffer
+	panics        *panicScanner
+
+	runMu     sync.Mutex
+	runCancel context.CancelCauseFunc
 
-	initialize   api.Function
-	goStartMain  api.Function
-	goTick       api.Function
+	created   time.Time
+	startedAt time.Time
+	started   atomic.Bool
+	// tickedSinceStart is set by the first tick after StartMain.
+	tickedSinceStart atomic.Bool
+	idledOnce        atomic.Bool
+	closed           atomic.Bool
+	ticks            atomic.Uint64
+	// lastTick is the unix nano time of the last go_tick, zero if none.
+	lastTick   atomic.Int64
+	lastResult atomic.Int32
+	memPages   atomic.Uint32
+	// peakPages is the high-water mark of memPages since the last ResetStats.
+	peakPages atomic.Uint32
+	exited    atomic.Bool
+	exitCode  atomic.Uint32
+	trapped   atomic.Bool
+	// tickTime is the total wall time spent in go_tick, in nanoseconds.
+	tickTime  atomic.Int64
+	budgeted  atomic.Bool
+	host      *hostState
+	wasiHooks *wasiHooks
+	secrets   *secretStore
+	// metricNames interns names for Config.Metrics, nil if unset.
+	metricNames *metricNames
+	// tickOutput counts bytes written to stdout and stderr in the current
+	// tick, if Config.MaxOutputPerTick is set.
+	tickOutput *atomic.Int64
+	// bytesIn and bytesOut count guest I/O if Config.CountIO is set.
+	bytesIn  *atomic.Int64
+	bytesOut *atomic.Int64
+	// stdinEOF is set once Stdin returned EOF, if Config.StopOnStdinEOF.
+	stdinEOF *atomic.Bool
+	// lastRun describes the last completed run, guarded by runMu.
+	lastRun lastRun
+	// lastSnapshot is when Config.Store was last saved to by the run loop.
+	lastSnapshot time.Time
+	// timerDue and notifiedAt are the unix nano times a guest timer fell due
+	// and Notify was first called since the last tick, for wake latency.
+	timerDue   atomic.Int64
+	notifiedAt atomic.Int64
+	// stdinPipe feeds stdin if Config.StdinBufferLimit is set.
+	stdinPipe *stdinPipe
+	// termErr is the error from the guest call that ended the guest.
+	termErr atomic.Pointer[error]
+	// records decodes Config.RecordFD, if set.
+	records *recordWriter
+	// ownsRuntime is set by NewReactorStandalone, so Close closes runtime.
+	ownsRuntime bool
 }
 
 // NewReactor instantiates a Go WASI reactor from the given WASM bytes.
+// ctx is used for compilation, instantiation, and the _initialize call.
+//
+// To instantiate the same module many times, use Compile and
+// CompiledReactor.Instantiate instead, which compiles only once.
 func NewReactor(ctx context.Context, r wazero.Runtime, wasm []byte, cfg *Config) (*Reactor, error) {
+	start := time.Now()
+	compiled, err := Compile(ctx, r, wasm)
+	if err != nil {
+		return nil, err
+	}
+	// wazero finishes a compile even if ctx is done meanwhile
+	if err := ctx.Err(); err != nil {
+		_ = compiled.Close(ctx)
+		return nil, err
+	}
+	if cfg != nil && cfg.Timings != nil {
+		cfg.Timings.Compile = time.Since(start)
+	}
+	return compiled.Instantiate(ctx, cfg)
+}
+
+// NewReactorStandalone is like NewReactor but creates its own runtime, which
+// Close closes along with the reactor, for one-off executions where managing
+// a wazero.Runtime is not worth it. The runtime uses wazero's default
+// configuration, which selects the optimizing compiler where supported.
+//
+// Every call compiles wasm afresh, so this trades away sharing the compiled
+// module between reactors for simplicity; use NewReactor or Compile to run a
+// module more than once.
+func NewReactorStandalone(ctx context.Context, wasm []byte, cfg *Config) (*Reactor, error) {
+	rt := wazero.NewRuntime(ctx)
+	reactor, err := NewReactor(ctx, rt, wasm, cfg)
+	if err != nil {
+		_ = rt.Close(ctx)
+		return nil, err
+	}
+	reactor.ownsRuntime = true
+	return reactor, nil
+}
+
+// Instantiate creates a new Reactor from the compiled module and calls
+// _initialize, or restores the snapshot in Config.Store if there is one.
+// ctx is used for instantiation and the _initialize call.
+func (c *CompiledReactor) Instantiate(ctx context.Context, cfg *Config) (*Reactor, error) {
+	return c.instantiateReactor(ctx, cfg, nil)
+}
+
+// instantiateReactor implements Instantiate, restoring snapshot instead of
+// calling _initialize if it is not empty.
+func (c *CompiledReactor) instantiateReactor(ctx context.Context, cfg *Config, snapshot []byte) (*Reactor, error) {
 	if cfg == nil {
 		cfg = &Config{}
 	}
 
-	// Set defaults
-	stdin := cfg.Stdin
-	if stdin == nil {
-		stdin = os.Stdin
+	if err := c.checkExports(cfg.AllowedExports); err != nil {
+		return nil, err
 	}
-	stdout := cfg.Stdout
-	if stdout == nil {
-		stdout = os.Stdout
+
+	stdin, stdout, stderr := cfg.stdio()
+	var records *recordWriter
+	switch cfg.RecordFD {
+	case 0:
+	case 1:
+		records = newRecordWriter()
+		stdout = records
+	case 2:
+		records = newRecordWriter()
+		stderr = records
+	default:
+		return nil, fmt.Errorf("record fd %d: must be 1 (stdout) or 2 (stderr)", cfg.RecordFD)
 	}
-	stderr := cfg.Stderr
-	if stderr == nil {
-		stderr = os.Stderr
+	var pipe *stdinPipe
+	if cfg.StdinBufferLimit > 0 {
+		pipe = newStdinPipe(cfg.StdinBufferLimit)
+		stdin = pipe
 	}
-	args := cfg.Args
-	if len(args) == 0 {
-		args = []string{"reactor"}
+	var panics *panicScanner
+	if cfg.CapturePanic {
+		panics = newPanicScanner(stderr)
+		stderr = panics
 	}
-
-	// Instantiate WASI
-	if _, err := wasi_snapshot_preview1.Instantiate(ctx, r); err != nil {
-		return nil, fmt.Errorf("instantiate WASI: %w", err)
+	if cfg.NormalizeLineEndings&NormalizeStdout != 0 {
+		stdout = &lineNormalizer{w: stdout}
 	}
-
-	// Compile the module
-	compiled, err := r.CompileModule(ctx, wasm)
-	if err != nil {
-		return nil, fmt.Errorf("compile module: %w", err)
+	if cfg.NormalizeLineEndings&NormalizeStderr != 0 {
+		stderr = &lineNormalizer{w: stderr}
 	}
-
-	// Configure the module
-	modConfig := wazero.NewModuleConfig().
-		WithStdin(stdin).
-		WithStdout(stdout).
-		WithStderr(stderr).
-		WithArgs(args...).
-		WithStartFunctions() // Don't call _start automatically
-
-	for _, env := range cfg.Env {
-		// Parse KEY=VALUE
-		for i := 0; i < len(env); i++ {
-			if env[i] == '=' {
-				modConfig = modConfig.WithEnv(env[:i], env[i+1:])
-				break
-			}
+	var stdoutCapture, stderrCapture *captureBuffer
+	if cfg.CaptureOutput {
+		stdoutCapture = &captureBuffer{limit: cfg.CaptureLimit}
+		stderrCapture = &captureBuffer{limit: cfg.CaptureLimit}
+		outCapture := &captureWriter{w: stdout, buf: stdoutCapture}
+		errCapture := &captureWriter{w: stderr, buf: stderrCapture}
+		if cfg.NormalizeLineEndings&NormalizeCaptured != 0 {
+			outCapture.norm = &lineNormalizer{w: stdoutCapture}
+			errCapture.norm = &lineNormalizer{w: stderrCapture}
 		}
+		stdout, stderr = outCapture, errCapture
 	}
+	var stdinEOF *atomic.Bool
+	if cfg.StopOnStdinEOF {
+		stdinEOF = new(atomic.Bool)
+		stdin = &eofReader{r: stdin, eof: stdinEOF}
+	}
+	var bytesIn, bytesOut *atomic.Int64
+	if cfg.CountIO {
+		bytesIn, bytesOut = new(atomic.Int64), new(atomic.Int64)
+		stdin = &countingReader{r: stdin, n: bytesIn}
+		stdout = &countingWriter{w: stdout, n: bytesOut}
+		stderr = &countingWriter{w: stderr, n: bytesOut}
+	}
+	var tickOutput *atomic.Int64
+	if cfg.MaxOutputPerTick > 0 {
+		tickOutput = new(atomic.Int64)
+		stdout = &countingWriter{w: stdout, n: tickOutput}
+		stderr = &countingWriter{w: stderr, n: tickOutput}
+	}
+	var outputs []*outputWriter
+	if cfg.OnOutputError != nil {
+		outputs = []*outputWriter{
+			{w: stdout, onError: cfg.OnOutputError},
+			{w: stderr, onError: cfg.OnOutputError},
+		}
+		stdout, stderr = outputs[0], outputs[1]
+	}
+	wait := cfg.WaitStrategy
+	if wait == nil {
+		wait = TimerWait{}
+	}
+	releaseLimiter := func() {}
 
-	if cfg.FS != nil {
-		modConfig = modConfig.WithFSConfig(cfg.FS)
+	if l := cfg.InstantiateLimiter; l != nil {
+		if err := l.Acquire(ctx); err != nil {
+			return nil, fmt.Errorf("wait for instantiate limiter: %w", err)
+		}
+		var once sync.Once
+		release := func() { once.Do(l.Release) }
+		defer release()
+		releaseLimiter = release
 	}
 
-	// Instantiate the module
-	mod, err := r.InstantiateModule(ctx, compiled, modConfig)
+	host := cfg.newHostState()
+	start := time.Now()
+	mod, err := c.instantiate(ctx, cfg, stdin, stdout, stderr, host)
 	if err != nil {
-		return nil, fmt.Errorf("instantiate module: %w", err)
+		return nil, err
+	}
+
+	if cfg.Timings != nil {
+		cfg.Timings.Instantiate = time.Since(start)
 	}
 
 	// Look up exported functions
@@ -153,51 +826,569 @@ func NewReactor(ctx context.Context, r wazero.Runtime, wasm []byte, cfg *Config)
 	}
 
 	reactor := &Reactor{
-		runtime:     r,
+		runtime:     c.runtime,
+		compiled:    c,
 		mod:         mod,
 		initialize:  initialize,
 		goStartMain: goStartMain,
 		goTick:      goTick,
+		goWantsTick: mod.ExportedFunction("go_wants_tick"),
+		cfg:         *cfg,
+		stdout:      stdout,
+		stderr:      stderr,
+		wait:        wait,
+		wake:        make(chan struct{}, 1),
+		host:        host,
+		wasiHooks:   cfg.newWASIHooks(host.files),
+		secrets:     cfg.newSecretStore(),
+		tickOutput:  tickOutput,
+		bytesIn:     bytesIn,
+		stdinEOF:    stdinEOF,
+		stdinPipe:   pipe,
+		records:     records,
+		bytesOut:    bytesOut,
+
+		stdoutCapture: stdoutCapture,
+		stderrCapture: stderrCapture,
+		panics:        panics,
+		created:       time.Now(),
+	}
+	reactor.cfg.Metadata = maps.Clone(cfg.Metadata)
+	for _, o := range outputs {
+		o.r = reactor
+	}
+	if cfg.Metrics != nil {
+		reactor.metricNames = &metricNames{names: make(map[string]string)}
+	}
+	if mem := mod.Memory(); mem != nil {
+		if pages := mem.Size() / wasmPageSize; cfg.InitialMemoryPages > pages {
+			if _, ok := mem.Grow(cfg.InitialMemoryPages - pages); !ok {
+				mod.Close(ctx)
+				return nil, fmt.Errorf("grow memory to %d pages: exceeds module maximum", cfg.InitialMemoryPages)
+			}
+		}
+		reactor.memPages.Store(mem.Size() / wasmPageSize)
+		reactor.peakPages.Store(mem.Size() / wasmPageSize)
+	}
+
+	if len(snapshot) == 0 && cfg.Store != nil {
+		if snapshot, err = cfg.Store.Load(); err != nil {
+			mod.Close(ctx)
+			return nil, fmt.Errorf("load snapshot: %w", err)
+		}
+	}
+
+	// Call _initialize, or resume from the snapshot
+	start = time.Now()
+	if len(snapshot) != 0 {
+		if err := reactor.restore(snapshot); err != nil {
+			mod.Close(ctx)
+			return nil, fmt.Errorf("restore snapshot: %w", err)
+		}
+		reactor.lastSnapshot = time.Now()
+	} else if err := reactor.callInitialize(ctx); err != nil {
+		mod.Close(ctx)
+		return nil, err
+	}
+	if cfg.Timings != nil {
+		cfg.Timings.Initialize = time.Since(start)
+	}
+	releaseLimiter()
+	reactor.pollMemory()
+
+	// Checked after _initialize, as a Go guest cannot run exported
+	// functions before its runtime is initialized
+	if err := reactor.checkABIVersion(ctx); err != nil {
+		mod.Close(ctx)
+		return nil, err
+	}
+
+	if cfg.GoroutinesPerTick > 0 {
+		if err := reactor.SetTickBudget(ctx, cfg.GoroutinesPerTick); err != nil {
+			mod.Close(ctx)
+			return nil, fmt.Errorf("set tick budget: %w", err)
+		}
 	}
 
-	// Call _initialize
-	if _, err := initialize.Call(ctx); err != nil {
+	if err := reactor.checkMaxProcs(ctx); err != nil {
 		mod.Close(ctx)
-		return nil, fmt.Errorf("call _initialize: %w", err)
+		return nil, err
+	}
+
+	if cfg.SchedulerSeed != 0 {
+		err := reactor.setSchedulerSeed(ctx, cfg.SchedulerSeed)
+		if err != nil && (cfg.StrictSchedulerSeed || !errors.Is(err, ErrUnsupported)) {
+			mod.Close(ctx)
+			return nil, fmt.Errorf("set scheduler seed: %w", err)
+		}
 	}
 
+	if cfg.AutoStart {
+		if err := reactor.StartMain(ctx); err != nil {
+			mod.Close(ctx)
+			return nil, fmt.Errorf("start main: %w", err)
+		}
+	}
+
+	if cfg.Registry != nil {
+		cfg.Registry.add(reactor)
+	}
+	reactor.joinBudget()
+
 	return reactor, nil
 }
 
+// callInitialize calls _initialize, applying Config.InitTimeout.
+func (r *Reactor) callInitialize(ctx context.Context) error {
+	if r.cfg.InitTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, r.cfg.InitTimeout, ErrInitTimeout)
+		defer cancel()
+	}
+	_, err := r.call(ctx, r.initialize)
+	if errors.Is(context.Cause(ctx), ErrInitTimeout) {
+		return ErrInitTimeout
+	}
+	if err != nil {
+		return fmt.Errorf("call _initialize: %w", err)
+	}
+	return nil
+}
+
+// stdio returns the configured standard streams with defaults applied.
+func (cfg *Config) stdio() (stdin io.Reader, stdout, stderr io.Writer) {
+	stdin = cfg.Stdin
+	if cfg.StdinFunc != nil {
+		stdin = readerFunc(cfg.StdinFunc)
+	}
+	if stdin == nil {
+		stdin = os.Stdin
+	}
+	stdout = cfg.Stdout
+	if stdout == nil {
+		stdout = os.Stdout
+	}
+	stderr = cfg.Stderr
+	if stderr == nil {
+		stderr = os.Stderr
+	}
+	return stdin, stdout, stderr
+}
+
+// readerFunc adapts a function to io.Reader.
+type readerFunc func(p []byte) (int, error)
+
+// Read implements io.Reader.
+func (f readerFunc) Read(p []byte) (int, error) {
+	return f(p)
+}
+
+// instantiate prepares the runtime and instantiates the compiled module with
+// the given standard streams and the rest of cfg, with host hooks reporting to
+// host. No start function is called.
+func (c *CompiledReactor) instantiate(ctx context.Context, cfg *Config, stdin io.Reader, stdout, stderr io.Writer, host *hostState) (api.Module, error) {
+	r := c.runtime
+	caps := cfg.capabilities()
+	args := cfg.Args
+	if len(args) == 0 {
+		args = []string{"reactor"}
+	}
+	if !caps.AllowArgs {
+		args = args[:1]
+	}
+
+	// Instantiate WASI, unless an earlier reactor on this runtime already did
+	if r.Module(wasi_snapshot_preview1.ModuleName) == nil {
+		if _, err := wasi_snapshot_preview1.Instantiate(withWASIListener(ctx), r); err != nil {
+			return nil, fmt.Errorf("instantiate WASI: %w", err)
+		}
+	}
+
+	if cfg.HostModuleSet != nil {
+		if err := cfg.HostModuleSet.instantiate(ctx, r); err != nil {
+			return nil, err
+		}
+	}
+	if err := instantiateHostModules(ctx, r, cfg.hostModules()); err != nil {
+		return nil, err
+	}
+
+	// Configure the module
+	modConfig := wazero.NewModuleConfig().
+		WithStdin(stdin).
+		WithStdout(stdout).
+		WithStderr(stderr).
+		WithArgs(args...).
+		WithStartFunctions() // Don't call _start automatically
+
+	if caps.AllowEnv {
+		for _, env := range cfg.Env {
+			// Parse KEY=VALUE
+			for i := 0; i < len(env); i++ {
+				if env[i] == '=' {
+					if cfg.WorkDir == "" || env[:i] != "PWD" {
+						modConfig = modConfig.WithEnv(env[:i], env[i+1:])
+					}
+					break
+				}
+			}
+		}
+	}
+	if cfg.WorkDir != "" {
+		modConfig = modConfig.WithEnv("PWD", cfg.WorkDir)
+	}
+
+	if caps.AllowFS {
+		fsConfig := cfg.FS
+		if fsConfig != nil && cfg.MaxOpenFiles > 0 {
+			return nil, errors.New("MaxOpenFiles cannot limit files opened through FS; use Mounts instead")
+		}
+		var mounts []Mount
+		if cfg.ProfileDir != "" {
+			mounts = append(mounts, Mount{GuestPath: ProfileMountPath, Dir: cfg.ProfileDir})
+		}
+		mounts = append(mounts, cfg.Mounts...)
+		if len(cfg.OverlayFS) > 0 {
+			mounts = append(mounts, Mount{GuestPath: "/", FS: overlayFS(cfg.OverlayFS)})
+		}
+		if len(mounts) > 0 {
+			var err error
+			if fsConfig, err = withMounts(fsConfig, mounts, host.files); err != nil {
+				return nil, err
+			}
+		}
+		if fsConfig != nil {
+			modConfig = modConfig.WithFSConfig(fsConfig)
+		}
+	}
+
+	if rand := cfg.randSource(caps, host); rand != nil {
+		modConfig = modConfig.WithRandSource(rand)
+	}
+
+	if caps.AllowClock && (cfg.Clock != nil || cfg.ClockResolution > 0) {
+		source := hostNanotime
+		res := cfg.ClockResolution.Nanoseconds()
+		if res <= 0 {
+			res = 1
+		}
+		if cfg.Clock != nil {
+			source = cfg.Clock.Nanotime
+			modConfig = modConfig.WithWalltime(cfg.Clock.Walltime, sys.ClockResolution(res))
+		}
+		nanotime := func() int64 {
+			now := source()
+			return now - now%res
+		}
+		modConfig = modConfig.WithNanotime(nanotime, sys.ClockResolution(res))
+	}
+
+	// Instantiate the module
+	backoff := cfg.InstantiateBackoff
+	if backoff <= 0 {
+		backoff = DefaultInstantiateBackoff
+	}
+	for attempt := 0; ; attempt++ {
+		mod, err := r.InstantiateModule(ctx, c.compiled, modConfig)
+		if err == nil {
+			return mod, nil
+		}
+		if attempt >= cfg.InstantiateRetries || !errors.Is(err, syscall.ENOMEM) {
+			return nil, &InstantiateError{Err: err}
+		}
+		cfg.logger().Warn("instantiate failed, retrying",
+			"reactor", cfg.Name, "attempt", attempt+1, "backoff", backoff, "error", err)
+		select {
+		case <-ctx.Done():
+			return nil, &InstantiateError{Err: err}
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// DefaultInstantiateBackoff is the first retry delay used when
+// Config.InstantiateRetries is set and Config.InstantiateBackoff is zero.
+const DefaultInstantiateBackoff = 10 * time.Millisecond
+
+// nanotimeBase anchors hostNanotime to the process start.
+var nanotimeBase = time.Now()
+
+// hostNanotime returns the host's monotonic clock in nanoseconds.
+func hostNanotime() int64 {
+	return int64(time.Since(nanotimeBase))
+}
+
 // Close releases resources associated with the reactor.
+// The reactor is removed from its Registry, if any, and its memory is
+// released from its Budget. A reactor from NewReactorStandalone also closes
+// its runtime.
 func (r *Reactor) Close(ctx context.Context) error {
-	return r.mod.Close(ctx)
+	r.closed.Store(true)
+	if r.cfg.Registry != nil {
+		r.cfg.Registry.remove(r)
+	}
+	r.leaveBudget()
+	if r.stdinPipe != nil {
+		r.stdinPipe.closeRead()
+	}
+	if r.records != nil {
+		r.records.close()
+	}
+	err := r.mod.Close(ctx)
+	if r.ownsRuntime {
+		err = errors.Join(err, r.runtime.Close(ctx))
+	}
+	return err
 }
 
 // StartMain queues the main goroutine for execution.
-// This must be called before Run or LoopOnce.
+// This must be called before LoopOnce; Run calls it unless Config.ManualStart
+// is set, and Instantiate calls it if Config.AutoStart is set. Calls after the
+// first successful one do nothing.
 func (r *Reactor) StartMain(ctx context.Context) error {
-	_, err := r.goStartMain.Call(ctx)
+	if r.started.Load() {
+		return nil
+	}
+	startedAt := time.Now()
+	_, err := r.call(ctx, r.goStartMain)
+	if err == nil {
+		r.startedAt = startedAt
+		r.started.Store(true)
+	}
+	return err
+}
+
+// SetTickBudget sets the maximum number of goroutines the guest scheduler runs
+// per go_tick. See Config.GoroutinesPerTick for the trade-off.
+// Returns ErrUnsupported if the guest does not export go_set_tick_budget.
+func (r *Reactor) SetTickBudget(ctx context.Context, n int) error {
+	fn, err := r.optionalExport("go_set_tick_budget")
+	if err != nil {
+		return err
+	}
+	_, err = r.call(ctx, fn, api.EncodeI32(int32(n)))
+	return err
+}
+
+// checkMaxProcs applies Config.MaxProcs and warns if the guest believes it can
+// run goroutines in parallel.
+func (r *Reactor) checkMaxProcs(ctx context.Context) error {
+	if r.cfg.MaxProcs > 0 {
+		if fn := r.mod.ExportedFunction("go_set_maxprocs"); fn != nil {
+			if _, err := r.call(ctx, fn, api.EncodeI32(int32(r.cfg.MaxProcs))); err != nil {
+				return fmt.Errorf("go_set_maxprocs: %w", err)
+			}
+		} else {
+			r.logger().Debug("guest does not export go_set_maxprocs; ignoring MaxProcs")
+		}
+	}
+
+	fn := r.mod.ExportedFunction("go_gomaxprocs")
+	if fn == nil {
+		return nil
+	}
+	results, err := r.call(ctx, fn)
+	if err != nil {
+		return fmt.Errorf("go_gomaxprocs: %w", err)
+	}
+	if procs := api.DecodeI32(results[0]); procs > 1 {
+		r.logger().Warn("guest reports GOMAXPROCS > 1 but reactors run single-threaded",
+			"reactor", r.cfg.Name, "gomaxprocs", procs)
+	}
+	return nil
+}
+
+// setSchedulerSeed seeds the guest scheduler via go_set_sched_seed.
+func (r *Reactor) setSchedulerSeed(ctx context.Context, seed uint64) error {
+	fn, err := r.optionalExport("go_set_sched_seed")
+	if err != nil {
+		return err
+	}
+	_, err = r.call(ctx, fn, seed)
 	return err
 }
 
 // LoopOnce runs one iteration of the Go scheduler.
 // Returns the result indicating when to call again.
+//
+// If a host function panics during the tick, the reactor is closed and a
+// *HostCallbackError is returned. If the reactor's Budget is exceeded, the
+// guest is not ticked and ErrBudgetExceeded is returned.
 func (r *Reactor) LoopOnce(ctx context.Context) (LoopResult, error) {
-	results, err := r.goTick.Call(ctx)
+	raw, err := r.LoopOnceRaw(ctx)
 	if err != nil {
 		return LoopIdle, err
 	}
-	return LoopResult(int32(results[0])), nil
+	return LoopResult(raw), nil
+}
+
+// LoopOnceRaw runs one iteration of the Go scheduler like LoopOnce, but
+// returns go_tick's result untouched for callers with their own loop
+// semantics. By convention, -1 means idle, 0 means more goroutines are
+// runnable, and a positive value is the number of milliseconds until the next
+// guest timer, with math.MaxInt32 meaning no timer worth waiting for. Other
+// negative values are reserved and never produced by the current ABI.
+func (r *Reactor) LoopOnceRaw(ctx context.Context) (int32, error) {
+	if b := r.cfg.Budget; b != nil && b.Exceeded() {
+		return 0, ErrBudgetExceeded
+	}
+	if r.tickOutput != nil {
+		r.tickOutput.Store(0)
+	}
+	tickCtx, stopWatchdog := r.startWatchdog(ctx)
+	start := time.Now()
+	if r.cfg.Metrics != nil {
+		r.observeWake(start)
+	}
+	results, err := r.call(tickCtx, r.goTick)
+	stopWatchdog()
+	if err != nil {
+		return 0, err
+	}
+	raw := api.DecodeI32(results[0])
+	now := time.Now()
+	r.ticks.Add(1)
+	r.tickTime.Add(int64(now.Sub(start)))
+	if r.cfg.Budget != nil {
+		r.cfg.Budget.charge(now.Sub(start))
+	}
+	r.lastResult.Store(raw)
+	r.lastTick.Store(now.UnixNano())
+	r.pollMemory()
+	// Ticks before StartMain, e.g. with ManualStart, do not count as first
+	firstAfterStart := r.started.Load() && r.tickedSinceStart.CompareAndSwap(false, true)
+	if raw == int32(LoopIdle) && firstAfterStart && r.cfg.WarnOnImmediateIdle {
+		r.logger().Warn("first tick after start_main reported idle; was main linked into the guest?")
+	}
+	if raw == int32(LoopIdle) && r.idledOnce.CompareAndSwap(false, true) && r.cfg.OnFirstIdle != nil {
+		since := r.created
+		if r.started.Load() {
+			since = r.startedAt
+		}
+		r.cfg.OnFirstIdle(time.Since(since))
+	}
+	return raw, nil
 }
 
 // Run executes the reactor until completion.
-// It calls StartMain, then loops calling go_tick until idle.
+// It calls StartMain (unless Config.ManualStart is set), then loops calling
+// go_tick until idle. If ctx is already done, Run returns its error without
+// calling into the guest.
 func (r *Reactor) Run(ctx context.Context) error {
-	if err := r.StartMain(ctx); err != nil {
-		return fmt.Errorf("start main: %w", err)
+	return r.run(ctx, nil, false)
+}
+
+// RunWithCallback executes the reactor, calling onTick before each iteration.
+// This allows the host to perform work between scheduler iterations.
+// If onTick panics, the reactor is closed and a *HostCallbackError is returned.
+func (r *Reactor) RunWithCallback(ctx context.Context, onTick func()) error {
+	return r.run(ctx, onTick, false)
+}
+
+// Serve executes the reactor like Run, but when the guest goes idle it parks
+// until Notify is called instead of returning. This suits long-lived guests
+// that wait on host events.
+//
+// Serve returns when ctx is done, the guest fails or exits, or the reactor
+// is closed by Config.IdleTimeout, in which case it returns ErrIdleTimeout.
+func (r *Reactor) Serve(ctx context.Context) error {
+	return r.run(ctx, nil, true)
+}
+
+// Notify asks for the guest to be ticked again. It wakes the reactor if it is
+// parked in Serve or waiting on a timer, and if called during a tick, e.g. by
+// a host function that queued work for the guest, the next wait is skipped.
+// It is safe to call from any goroutine and never blocks.
+func (r *Reactor) Notify() {
+	if r.cfg.Metrics != nil {
+		r.notifiedAt.CompareAndSwap(0, time.Now().UnixNano())
+	}
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// pollMemory records the guest's memory size, reporting any growth.
+func (r *Reactor) pollMemory() {
+	mem := r.mod.Memory()
+	if mem == nil {
+		return
+	}
+	pages := mem.Size() / wasmPageSize
+	old := r.memPages.Swap(pages)
+	if pages > r.peakPages.Load() {
+		r.peakPages.Store(pages)
+	}
+	if pages <= old {
+		return
+	}
+	if r.budgeted.Load() {
+		r.cfg.Budget.pages.Add(uint64(pages - old))
+	}
+	if r.cfg.OnMemoryGrow != nil {
+		r.cfg.OnMemoryGrow(old, pages)
+	}
+}
+
+// run implements Run, RunWithCallback, and Serve.
+func (r *Reactor) run(ctx context.Context, onTick func(), serve bool) error {
+	// Leave the guest alone if the caller already gave up
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if r.cfg.MaxRunDuration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, r.cfg.MaxRunDuration, ErrRunTimeout)
+		defer cancel()
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	r.setRunCancel(cancel)
+	defer r.setRunCancel(nil)
+
+	start := time.Now()
+	err := r.runLoop(ctx, onTick, serve)
+	r.runMu.Lock()
+	r.lastRun = lastRun{done: true, duration: time.Since(start), err: err}
+	r.runMu.Unlock()
+	return err
+}
+
+// runLoop starts main if needed and runs loop, mapping cancellation causes to
+// the errors run returns.
+func (r *Reactor) runLoop(ctx context.Context, onTick func(), serve bool) error {
+	var err error
+	if !r.cfg.ManualStart {
+		err = r.StartMain(ctx)
+	}
+	if err != nil {
+		err = fmt.Errorf("start main: %w", err)
+	} else {
+		err = r.loop(ctx, onTick, serve)
+	}
+	if err == nil {
+		return nil
 	}
 
+	cause := context.Cause(ctx)
+	var cancelErr *CanceledError
+	switch {
+	case errors.As(cause, &cancelErr):
+		r.finishCancel(ctx, cancelErr.Reason)
+		return cancelErr
+	case errors.Is(cause, ErrRunTimeout):
+		return ErrRunTimeout
+	}
+	return err
+}
+
+// loop drives the scheduler for run.
+func (r *Reactor) loop(ctx context.Context, onTick func(), serve bool) error {
+	var ticks uint64
+	var ready int
 	for {
 		select {
 		case <-ctx.Done():
@@ -205,70 +1396,318 @@ func (r *Reactor) Run(ctx context.Context) error {
 		default:
 		}
 
+		if r.cfg.MaxTicks > 0 && ticks >= r.cfg.MaxTicks {
+			return ErrTickLimitExceeded
+		}
+		ticks++
+
+		if onTick != nil {
+			if err := callHostCallback("onTick", onTick); err != nil {
+				_ = r.Close(ctx)
+				return err
+			}
+		}
+
 		result, err := r.LoopOnce(ctx)
 		if err != nil {
+			if r.cfg.StateObserver != nil && r.exited.Load() && !errors.Is(err, ErrReactorTerminated) {
+				r.cfg.StateObserver.OnExit(r.exitCode.Load())
+			}
 			return fmt.Errorf("loop once: %w", err)
 		}
+		if r.cfg.StateObserver != nil {
+			r.observeResult(result)
+		}
+
+		if result != LoopReady {
+			ready = 0
+		}
+
+		retick, err := r.afterTick(ctx, result)
+		if err != nil {
+			return err
+		}
+		if retick {
+			continue
+		}
 
 		switch {
 		case result == LoopIdle:
-			return nil
+			if !serve || r.stdinDone() {
+				return nil
+			}
+			if err := r.park(ctx); err != nil {
+				return err
+			}
 		case result == LoopReady:
 			// More work, continue immediately
+			ready++
+			if r.cfg.YieldEvery > 0 && ready%r.cfg.YieldEvery == 0 {
+				runtime.Gosched()
+			}
 			continue
+		case result == LoopWaitForever:
+			if r.stdinDone() {
+				return nil
+			}
+			// No timer worth waiting for; wait to be notified
+			if err := r.waitNotify(ctx); err != nil {
+				return err
+			}
 		case result > 0:
 			// Wait for timer
-			timer := time.NewTimer(time.Duration(result) * time.Millisecond)
-			select {
-			case <-ctx.Done():
-				timer.Stop()
-				return ctx.Err()
-			case <-timer.C:
-				continue
+			if err := r.waitTimer(ctx, result); err != nil {
+				return err
 			}
 		}
 	}
 }
 
-// RunWithCallback executes the reactor, calling onTick before each iteration.
-// This allows the host to perform work between scheduler iterations.
-func (r *Reactor) RunWithCallback(ctx context.Context, onTick func()) error {
-	if err := r.StartMain(ctx); err != nil {
-		return fmt.Errorf("start main: %w", err)
+// afterTick does the run loop's housekeeping after a tick returned result,
+// and reports whether to tick again straight away although the guest is not
+// ready; see retickPending.
+func (r *Reactor) afterTick(ctx context.Context, result LoopResult) (bool, error) {
+	if r.cfg.FlushAfterTick {
+		if err := r.flushOutput(); err != nil {
+			return false, fmt.Errorf("flush output: %w", err)
+		}
 	}
 
-	for {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-		}
+	if err := r.maybeSnapshot(result == LoopIdle); err != nil {
+		return false, err
+	}
 
-		if onTick != nil {
-			onTick()
+	if result == LoopReady {
+		return false, nil
+	}
+	// Work may have been queued after the guest decided to wait
+	return r.retickPending(ctx)
+}
+
+// stdinDone reports whether Config.StopOnStdinEOF is set and the guest has
+// read EOF from stdin.
+func (r *Reactor) stdinDone() bool {
+	return r.stdinEOF != nil && r.stdinEOF.Load()
+}
+
+// retickPending reports whether another tick was requested since the last
+// go_tick decided to wait: either Notify was called, e.g. by a host function
+// that queued work during the tick, or the guest's optional go_wants_tick
+// export returns non-zero.
+func (r *Reactor) retickPending(ctx context.Context) (bool, error) {
+	select {
+	case <-r.wake:
+		return true, nil
+	default:
+	}
+	if r.goWantsTick == nil {
+		return false, nil
+	}
+	results, err := r.call(ctx, r.goWantsTick)
+	if err != nil {
+		return false, fmt.Errorf("go_wants_tick: %w", err)
+	}
+	return api.DecodeI32(results[0]) != 0, nil
+}
+
+// flushOutput flushes Stdout and Stderr if they implement Flusher.
+func (r *Reactor) flushOutput() error {
+	var errs []error
+	for _, w := range []io.Writer{r.stdout, r.stderr} {
+		if f, ok := w.(Flusher); ok {
+			if err := f.Flush(); err != nil {
+				errs = append(errs, err)
+			}
 		}
+	}
+	return errors.Join(errs...)
+}
 
-		result, err := r.LoopOnce(ctx)
-		if err != nil {
-			return fmt.Errorf("loop once: %w", err)
+// waitTimer waits for a pending guest timer, or advances Config.Clock past it
+// if the clock supports that.
+func (r *Reactor) waitTimer(ctx context.Context, result LoopResult) error {
+	d := time.Duration(result) * time.Millisecond
+	if clock, ok := r.cfg.Clock.(advancer); ok && r.cfg.capabilities().AllowClock {
+		clock.Advance(d)
+		return nil
+	}
+	if r.cfg.MinTimerWait > 0 {
+		d = max(d, r.cfg.MinTimerWait)
+	}
+	if r.cfg.MaxTimerWait > 0 {
+		d = min(d, r.cfg.MaxTimerWait)
+	}
+	if r.cfg.Metrics != nil {
+		r.timerDue.Store(time.Now().Add(d).UnixNano())
+	}
+	return r.wait.Wait(ctx, d, r.wake)
+}
+
+// waitNotify blocks until Notify is called or ctx is done.
+func (r *Reactor) waitNotify(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-r.wake:
+		return nil
+	}
+}
+
+// park blocks until Notify is called, ctx is done, or the idle timeout
+// elapses. On idle timeout the reactor is closed.
+func (r *Reactor) park(ctx context.Context) error {
+	var timeout <-chan time.Time
+	if r.cfg.IdleTimeout > 0 {
+		timer := time.NewTimer(r.cfg.IdleTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-r.wake:
+		return nil
+	case <-timeout:
+		if r.cfg.OnIdleTimeout != nil {
+			r.cfg.OnIdleTimeout()
 		}
+		_ = r.Close(ctx)
+		return ErrIdleTimeout
+	}
+}
 
-		switch {
-		case result == LoopIdle:
-			return nil
-		case result == LoopReady:
-			continue
-		case result > 0:
-			timer := time.NewTimer(time.Duration(result) * time.Millisecond)
-			select {
-			case <-ctx.Done():
-				timer.Stop()
-				return ctx.Err()
-			case <-timer.C:
-				continue
+// Ticks returns the number of go_tick calls that have completed.
+func (r *Reactor) Ticks() uint64 {
+	return r.ticks.Load()
+}
+
+// MemoryPages returns the size of the guest's linear memory in 64KiB pages,
+// as of the last tick.
+func (r *Reactor) MemoryPages() uint32 {
+	return r.memPages.Load()
+}
+
+// PeakMemoryPages returns the largest size of the guest's linear memory in
+// 64KiB pages seen at a tick since the reactor was created or ResetStats was
+// last called, for capacity planning.
+func (r *Reactor) PeakMemoryPages() uint32 {
+	return r.peakPages.Load()
+}
+
+// ResetStats resets high-water marks, such as PeakMemoryPages, to their
+// current values, e.g. to measure each request a long-lived reactor serves
+// separately. Counters such as Ticks are left alone.
+func (r *Reactor) ResetStats() {
+	r.peakPages.Store(r.memPages.Load())
+}
+
+// PendingTimers returns the number of timers queued in the guest runtime, as
+// reported by its optional go_pending_timers export, for diagnosing reactors
+// that seem stuck waiting. The second result is false if the guest does not
+// export it or the call fails.
+//
+// The count reflects the guest runtime's internal state and may be
+// approximate, e.g. including stopped timers not yet removed from the heap.
+// It must not be called concurrently with a tick.
+func (r *Reactor) PendingTimers(ctx context.Context) (int, bool) {
+	fn := r.mod.ExportedFunction("go_pending_timers")
+	if fn == nil {
+		return 0, false
+	}
+	results, err := r.call(ctx, fn)
+	if err != nil {
+		return 0, false
+	}
+	return int(api.DecodeI32(results[0])), true
+}
+
+// call calls a guest function, recording an exit or trap for Outcome. On exit,
+// Stdout and Stderr are flushed if they implement Flusher. A call interrupted
+// because ctx was done is neither an exit nor a trap, and returns an error
+// wrapping ctx's cause. If a host function panicked during the call, the
+// reactor is closed and the *HostCallbackError is returned. The error that
+// ended the guest is kept, and calls after it return ErrReactorTerminated
+// wrapping it.
+func (r *Reactor) call(ctx context.Context, fn api.Function, params ...uint64) ([]uint64, error) {
+	if err := r.terminated(); err != nil {
+		return nil, err
+	}
+	results, err := fn.Call(r.withCaller(ctx), params...)
+	if err != nil {
+		r.termErr.CompareAndSwap(nil, &err)
+		var exitErr *sys.ExitError
+		if errors.As(err, &exitErr) && interrupted(ctx, exitErr) {
+			// Not a proc_exit: wazero closed the module as ctx was done
+			if r.records != nil {
+				r.records.close()
 			}
+			return nil, fmt.Errorf("guest interrupted: %w", errors.Join(context.Cause(ctx), err))
 		}
+		if exitErr != nil {
+			r.exitCode.Store(exitErr.ExitCode())
+			r.exited.Store(true)
+			// wazero writes guest output straight through, but buffering
+			// writers would otherwise lose what the guest wrote last.
+			if ferr := r.flushOutput(); ferr != nil {
+				r.logger().Warn("flush output after exit failed", "reactor", r.cfg.Name, "error", ferr)
+			}
+			if r.records != nil {
+				r.records.close()
+			}
+			return nil, err
+		}
+		var cbErr *HostCallbackError
+		if errors.As(err, &cbErr) {
+			_ = r.Close(ctx)
+			return nil, cbErr
+		}
+		if errors.Is(err, ErrClockLimitExceeded) {
+			_ = r.Close(ctx)
+			return nil, ErrClockLimitExceeded
+		}
+		r.trapped.Store(true)
+		return nil, err
+	}
+	return results, nil
+}
+
+// interrupted reports whether exitErr is wazero closing the module because
+// ctx was done, with the runtime's WithCloseOnContextDone set, rather than the
+// guest calling proc_exit.
+func interrupted(ctx context.Context, exitErr *sys.ExitError) bool {
+	switch exitErr.ExitCode() {
+	case sys.ExitCodeContextCanceled, sys.ExitCodeDeadlineExceeded:
+		return ctx.Err() != nil
+	}
+	return false
+}
+
+// terminated returns ErrReactorTerminated, wrapping the error that ended the
+// guest, if it has exited, trapped, or been closed.
+func (r *Reactor) terminated() error {
+	if p := r.termErr.Load(); p != nil {
+		return fmt.Errorf("%w: %w", ErrReactorTerminated, *p)
+	}
+	if r.closed.Load() {
+		return fmt.Errorf("%w: closed", ErrReactorTerminated)
 	}
+	return nil
+}
+
+// optionalExport looks up an export used by an optional feature.
+func (r *Reactor) optionalExport(name string) (api.Function, error) {
+	fn := r.mod.ExportedFunction(name)
+	if fn == nil {
+		return nil, fmt.Errorf("%w: missing export %s", ErrUnsupported, name)
+	}
+	return fn, nil
+}
+
+// Metadata returns a copy of Config.Metadata, so callers cannot race with
+// each other by mutating it.
+func (r *Reactor) Metadata() map[string]any {
+	return maps.Clone(r.cfg.Metadata)
 }
 
 // Module returns the underlying wazero module for advanced usage.
@@ -0,0 +1,809 @@
+package reactor
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"path"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+// testImport is a function a test module imports.
+type testImport struct {
+	module, name    string
+	params, results []api.ValueType
+}
+
+// testFunc is a function defined by a test module.
+type testFunc struct {
+	// export is the name the function is exported as, or "" if it is not.
+	export          string
+	params, results []api.ValueType
+	// body is the function's instructions, without locals or the final end.
+	body []byte
+}
+
+// testModule describes a small wasm module, encoded by hand so tests need no
+// guest toolchain. Imported functions come first in the function index space.
+// The module exports one page of memory as "memory", and its globals are
+// mutable i32s starting at zero, exported as "g0", "g1" and so on.
+type testModule struct {
+	imports []testImport
+	funcs   []testFunc
+	globals int
+}
+
+// reactorModule returns a module exporting the reactor ABI, with go_tick
+// running tick, which must leave an i32 LoopResult on the stack, and any
+// extra functions after it.
+func reactorModule(tick []byte, extra ...testFunc) testModule {
+	return testModule{
+		funcs: append([]testFunc{
+			{export: "_initialize"},
+			{export: "go_start_main"},
+			{export: "go_tick", results: []api.ValueType{api.ValueTypeI32}, body: tick},
+		}, extra...),
+	}
+}
+
+// encode returns the module's wasm binary.
+func (m testModule) encode() []byte {
+	var types, imports, funcs, globals, exports, code []byte
+	for i, imp := range m.imports {
+		types = append(types, funcType(imp.params, imp.results)...)
+		imports = append(imports, wasmName(imp.module)...)
+		imports = append(imports, wasmName(imp.name)...)
+		imports = append(imports, 0x00)
+		imports = appendULEB(imports, uint64(i))
+	}
+	exports = append(exports, wasmName("memory")...)
+	exports = append(exports, 0x02, 0x00)
+	numExports := 1
+	for i, fn := range m.funcs {
+		idx := uint64(len(m.imports) + i)
+		types = append(types, funcType(fn.params, fn.results)...)
+		funcs = appendULEB(funcs, idx)
+		if fn.export != "" {
+			exports = append(exports, wasmName(fn.export)...)
+			exports = append(exports, 0x00)
+			exports = appendULEB(exports, idx)
+			numExports++
+		}
+		body := append([]byte{0x00}, fn.body...)
+		body = append(body, 0x0b)
+		code = appendULEB(code, uint64(len(body)))
+		code = append(code, body...)
+	}
+	for i := range m.globals {
+		globals = append(globals, 0x7f, 0x01, 0x41, 0x00, 0x0b)
+		exports = append(exports, wasmName(fmt.Sprintf("g%d", i))...)
+		exports = append(exports, 0x03)
+		exports = appendULEB(exports, uint64(i))
+		numExports++
+	}
+
+	out := []byte("\x00asm\x01\x00\x00\x00")
+	out = appendSection(out, 1, len(m.imports)+len(m.funcs), types)
+	if len(m.imports) > 0 {
+		out = appendSection(out, 2, len(m.imports), imports)
+	}
+	out = appendSection(out, 3, len(m.funcs), funcs)
+	out = appendSection(out, 5, 1, []byte{0x00, 0x01})
+	if m.globals > 0 {
+		out = appendSection(out, 6, m.globals, globals)
+	}
+	out = appendSection(out, 7, numExports, exports)
+	out = appendSection(out, 10, len(m.funcs), code)
+	return out
+}
+
+// fdWrite is WASI's fd_write(fd, iovs, iovs_len, nwritten) -> errno.
+var fdWrite = testImport{
+	module:  "wasi_snapshot_preview1",
+	name:    "fd_write",
+	params:  []api.ValueType{api.ValueTypeI32, api.ValueTypeI32, api.ValueTypeI32, api.ValueTypeI32},
+	results: []api.ValueType{api.ValueTypeI32},
+}
+
+// fdRead is WASI's fd_read(fd, iovs, iovs_len, nread) -> errno.
+var fdRead = testImport{
+	module:  "wasi_snapshot_preview1",
+	name:    "fd_read",
+	params:  fdWrite.params,
+	results: fdWrite.results,
+}
+
+// procExit is WASI's proc_exit(code).
+var procExit = testImport{
+	module: "wasi_snapshot_preview1",
+	name:   "proc_exit",
+	params: []api.ValueType{api.ValueTypeI32},
+}
+
+// pathOpen is WASI's path_open(fd, dirflags, path, path_len, oflags,
+// fs_rights_base, fs_rights_inheriting, fdflags, opened_fd) -> errno.
+var pathOpen = testImport{
+	module: "wasi_snapshot_preview1",
+	name:   "path_open",
+	params: []api.ValueType{
+		api.ValueTypeI32, api.ValueTypeI32, api.ValueTypeI32, api.ValueTypeI32, api.ValueTypeI32,
+		api.ValueTypeI64, api.ValueTypeI64, api.ValueTypeI32, api.ValueTypeI32,
+	},
+	results: []api.ValueType{api.ValueTypeI32},
+}
+
+// openFile returns instructions calling path_open, imported as function 0,
+// to open the path at 64 of length pathLen, relative to fd 3, the first
+// preopened directory, with all rights. The new fd is stored at 16 and the
+// errno left on the stack.
+func openFile(pathLen []byte) []byte {
+	return slices.Concat(
+		i32Const(3), i32Const(0), i32Const(64), pathLen, i32Const(0),
+		[]byte{0x42, 0x7f, 0x42, 0x7f}, // i64.const -1 twice
+		i32Const(0), i32Const(16), callFunc(0),
+	)
+}
+
+// clockTimeGet is WASI's clock_time_get(id, precision, time) -> errno.
+var clockTimeGet = testImport{
+	module:  "wasi_snapshot_preview1",
+	name:    "clock_time_get",
+	params:  []api.ValueType{api.ValueTypeI32, api.ValueTypeI64, api.ValueTypeI32},
+	results: []api.ValueType{api.ValueTypeI32},
+}
+
+// writeMem writes b to the guest's memory at offset.
+func writeMem(t testing.TB, r *Reactor, offset uint32, b []byte) {
+	t.Helper()
+	if !r.Module().Memory().Write(offset, b) {
+		t.Fatalf("write %d bytes at %d: out of range", len(b), offset)
+	}
+}
+
+// iovec encodes a WASI iovec of n bytes at buf.
+func iovec(buf, n uint32) []byte {
+	b := binary.LittleEndian.AppendUint32(nil, buf)
+	return binary.LittleEndian.AppendUint32(b, n)
+}
+
+// global returns the value of the guest's global i.
+func global(r *Reactor, i int) int32 {
+	return int32(r.Module().ExportedGlobal(fmt.Sprintf("g%d", i)).Get())
+}
+
+// appendSection appends a section holding a vector of n entries.
+func appendSection(out []byte, id byte, n int, entries []byte) []byte {
+	content := appendULEB(nil, uint64(n))
+	content = append(content, entries...)
+	out = append(out, id)
+	out = appendULEB(out, uint64(len(content)))
+	return append(out, content...)
+}
+
+// funcType encodes a function type.
+func funcType(params, results []api.ValueType) []byte {
+	b := []byte{0x60}
+	b = appendULEB(b, uint64(len(params)))
+	b = append(b, params...)
+	b = appendULEB(b, uint64(len(results)))
+	return append(b, results...)
+}
+
+// wasmName encodes a name.
+func wasmName(s string) []byte {
+	return append(appendULEB(nil, uint64(len(s))), s...)
+}
+
+// appendULEB appends v as unsigned LEB128.
+func appendULEB(b []byte, v uint64) []byte {
+	for {
+		c := byte(v & 0x7f)
+		v >>= 7
+		if v == 0 {
+			return append(b, c)
+		}
+		b = append(b, c|0x80)
+	}
+}
+
+// i32Const returns the instruction pushing v.
+func i32Const(v int32) []byte {
+	b := []byte{0x41}
+	for {
+		c := byte(v & 0x7f)
+		v >>= 7
+		if (v == 0 && c&0x40 == 0) || (v == -1 && c&0x40 != 0) {
+			return append(b, c)
+		}
+		b = append(b, c|0x80)
+	}
+}
+
+// i64Const returns the instruction pushing v.
+func i64Const(v int64) []byte {
+	b := []byte{0x42}
+	for {
+		c := byte(v & 0x7f)
+		v >>= 7
+		if (v == 0 && c&0x40 == 0) || (v == -1 && c&0x40 != 0) {
+			return append(b, c)
+		}
+		b = append(b, c|0x80)
+	}
+}
+
+// callFunc returns the instruction calling function idx.
+func callFunc(idx uint32) []byte {
+	return appendULEB([]byte{0x10}, uint64(idx))
+}
+
+// countTick returns a go_tick body that increments global 0 and runs first on
+// the first tick, which must push a LoopResult, and returns then on every
+// later tick.
+func countTick(first []byte, then LoopResult) []byte {
+	b := []byte{0x23, 0x00, 0x41, 0x01, 0x6a, 0x24, 0x00} // g0++
+	b = append(b, 0x23, 0x00, 0x41, 0x01, 0x46)           // g0 == 1
+	b = append(b, 0x04, 0x7f)                             // if (result i32)
+	b = append(b, first...)
+	b = append(b, 0x05) // else
+	b = append(b, i32Const(int32(then))...)
+	return append(b, 0x0b)
+}
+
+// runAsync runs r in the background, returning a channel receiving Run's
+// result.
+func runAsync(r *Reactor) <-chan error {
+	done := make(chan error, 1)
+	go func() { done <- r.Run(context.Background()) }()
+	return done
+}
+
+// waitRun waits for a run started by runAsync to return nil.
+func waitRun(t *testing.T, done <-chan error) {
+	t.Helper()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("run: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("run did not return")
+	}
+}
+
+// newReactor instantiates wasm on its own runtime, closed with the test.
+func newReactor(t testing.TB, wasm []byte, cfg *Config) (*Reactor, error) {
+	ctx := context.Background()
+	rt := wazero.NewRuntime(ctx)
+	t.Cleanup(func() { _ = rt.Close(ctx) })
+	return NewReactor(ctx, rt, wasm, cfg)
+}
+
+// newTestReactor instantiates m on its own runtime, closed with the test.
+func newTestReactor(t testing.TB, m testModule, cfg *Config) *Reactor {
+	t.Helper()
+	r, err := newReactor(t, m.encode(), cfg)
+	if err != nil {
+		t.Fatalf("new reactor: %v", err)
+	}
+	return r
+}
+
+func TestNewReactorCompileError(t *testing.T) {
+	_, err := newReactor(t, []byte("not a wasm module"), nil)
+	var compileErr *CompileError
+	if !errors.As(err, &compileErr) {
+		t.Fatalf("got %v, want a *CompileError", err)
+	}
+	if compileErr.Unwrap() == nil {
+		t.Error("CompileError does not wrap wazero's error")
+	}
+	var instErr *InstantiateError
+	if errors.As(err, &instErr) {
+		t.Errorf("garbage bytes reported as an instantiate error: %v", err)
+	}
+}
+
+func TestNewReactorInstantiateError(t *testing.T) {
+	m := reactorModule(i32Const(int32(LoopIdle)))
+	m.imports = []testImport{{module: "env", name: "missing"}}
+	_, err := newReactor(t, m.encode(), nil)
+	var instErr *InstantiateError
+	if !errors.As(err, &instErr) {
+		t.Fatalf("got %v, want an *InstantiateError", err)
+	}
+	if instErr.Unwrap() == nil {
+		t.Error("InstantiateError does not wrap wazero's error")
+	}
+	var compileErr *CompileError
+	if errors.As(err, &compileErr) {
+		t.Errorf("unsatisfied import reported as a compile error: %v", err)
+	}
+}
+
+func TestLoopOnceIdle(t *testing.T) {
+	r := newTestReactor(t, reactorModule(i32Const(int32(LoopIdle))), nil)
+	if err := r.Run(context.Background()); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if n := r.Ticks(); n != 1 {
+		t.Errorf("ticks = %d, want 1", n)
+	}
+}
+
+func TestLoopOncePanickingHostImport(t *testing.T) {
+	m := reactorModule(append(callFunc(0), i32Const(int32(LoopIdle))...))
+	m.imports = []testImport{{module: "test", name: "boom"}}
+	cfg := &Config{HostModules: []HostModule{{
+		Name: "test",
+		Functions: []HostFunction{{
+			Name: "boom",
+			Func: func(context.Context, api.Module, []uint64) { panic("boom") },
+		}},
+	}}}
+	r := newTestReactor(t, m, cfg)
+	ctx := context.Background()
+	if err := r.StartMain(ctx); err != nil {
+		t.Fatalf("start main: %v", err)
+	}
+
+	_, err := r.LoopOnce(ctx)
+	var cbErr *HostCallbackError
+	if !errors.As(err, &cbErr) {
+		t.Fatalf("got %v, want a *HostCallbackError", err)
+	}
+	if cbErr.Name != "test.boom" || cbErr.Value != "boom" {
+		t.Errorf("got callback %q panicking with %v, want test.boom with boom", cbErr.Name, cbErr.Value)
+	}
+	if len(cbErr.Stack) == 0 {
+		t.Error("no stack captured")
+	}
+	if _, err := r.LoopOnce(ctx); !errors.Is(err, ErrReactorTerminated) {
+		t.Errorf("tick after panic: got %v, want ErrReactorTerminated", err)
+	}
+}
+
+// noWait is a WaitStrategy that returns at once, so a run skips its timers.
+type noWait struct{}
+
+func (noWait) Wait(context.Context, time.Duration, <-chan struct{}) error { return nil }
+
+func TestRunWaitForeverBoundary(t *testing.T) {
+	tests := []struct {
+		name  string
+		first LoopResult
+		// waits reports whether Run waits for Notify rather than treating
+		// the result as a timer, which noWait skips.
+		waits bool
+	}{
+		{"largest timer", LoopWaitForever - 1, false},
+		{"wait forever", LoopWaitForever, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := reactorModule(countTick(i32Const(int32(tt.first)), LoopIdle))
+			m.globals = 1
+			r := newTestReactor(t, m, &Config{WaitStrategy: noWait{}})
+
+			done := runAsync(r)
+			if tt.waits {
+				select {
+				case err := <-done:
+					t.Fatalf("run returned %v without waiting for Notify", err)
+				case <-time.After(50 * time.Millisecond):
+				}
+				r.Notify()
+			}
+			waitRun(t, done)
+			if n := r.Ticks(); n != 2 {
+				t.Errorf("ticks = %d, want 2", n)
+			}
+		})
+	}
+}
+
+func TestRunRetick(t *testing.T) {
+	// Each guest queues work on its first tick after go_tick has already
+	// decided to wait: Run must tick again instead of returning on idle or
+	// sleeping through the hour-long timer.
+	t.Run("Notify", func(t *testing.T) {
+		m := reactorModule(countTick(append(callFunc(0), i32Const(int32(LoopIdle))...), LoopIdle))
+		m.imports = []testImport{{module: "test", name: "queue"}}
+		m.globals = 1
+		var r *Reactor
+		cfg := &Config{HostModules: []HostModule{{
+			Name: "test",
+			Functions: []HostFunction{{
+				Name: "queue",
+				Func: func(context.Context, api.Module, []uint64) { r.Notify() },
+			}},
+		}}}
+		r = newTestReactor(t, m, cfg)
+		waitRun(t, runAsync(r))
+		if n := r.Ticks(); n != 2 {
+			t.Errorf("ticks = %d, want 2", n)
+		}
+	})
+	t.Run("go_wants_tick", func(t *testing.T) {
+		wantsTick := testFunc{
+			export:  "go_wants_tick",
+			results: []api.ValueType{api.ValueTypeI32},
+			body:    []byte{0x23, 0x00, 0x41, 0x01, 0x46}, // g0 == 1
+		}
+		m := reactorModule(countTick(i32Const(int32(time.Hour/time.Millisecond)), LoopIdle), wantsTick)
+		m.globals = 1
+		r := newTestReactor(t, m, nil)
+		waitRun(t, runAsync(r))
+		if n := r.Ticks(); n != 2 {
+			t.Errorf("ticks = %d, want 2", n)
+		}
+	})
+}
+
+func TestLoopOnceAfterTrap(t *testing.T) {
+	r := newTestReactor(t, reactorModule([]byte{0x00}), nil) // unreachable
+	ctx := context.Background()
+	if err := r.StartMain(ctx); err != nil {
+		t.Fatalf("start main: %v", err)
+	}
+
+	_, trap := r.LoopOnce(ctx)
+	if trap == nil {
+		t.Fatal("tick did not trap")
+	}
+	_, err := r.LoopOnce(ctx)
+	if !errors.Is(err, ErrReactorTerminated) {
+		t.Fatalf("got %v, want ErrReactorTerminated", err)
+	}
+	if !errors.Is(err, trap) {
+		t.Errorf("got %v, want it to wrap the trap %v", err, trap)
+	}
+	if !strings.Contains(err.Error(), "unreachable") {
+		t.Errorf("error %q does not describe the trap", err)
+	}
+}
+
+// BenchmarkInitialMemoryPages compares instantiating a guest whose
+// _initialize grows memory a page at a time to 256 pages, as an
+// allocation-heavy Go runtime does at startup, with and without
+// Config.InitialMemoryPages sizing the memory up front.
+func BenchmarkInitialMemoryPages(b *testing.B) {
+	const pages = 256
+	m := reactorModule(i32Const(int32(LoopIdle)))
+	// block; loop; br_if 1 (memory.size >= pages); drop(memory.grow(1));
+	// br 0; end; end
+	m.funcs[0].body = slices.Concat(
+		[]byte{0x02, 0x40, 0x03, 0x40},
+		[]byte{0x3f, 0x00}, i32Const(pages), []byte{0x4f, 0x0d, 0x01},
+		i32Const(1), []byte{0x40, 0x00, 0x1a},
+		[]byte{0x0c, 0x00, 0x0b, 0x0b},
+	)
+	ctx := context.Background()
+	rt := wazero.NewRuntime(ctx)
+	defer rt.Close(ctx)
+	compiled, err := Compile(ctx, rt, m.encode())
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	for _, initial := range []uint32{0, pages} {
+		b.Run(fmt.Sprintf("InitialMemoryPages=%d", initial), func(b *testing.B) {
+			cfg := &Config{InitialMemoryPages: initial}
+			for range b.N {
+				r, err := compiled.Instantiate(ctx, cfg)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if n := r.MemoryPages(); n != pages {
+					b.Fatalf("memory is %d pages, want %d", n, pages)
+				}
+				_ = r.Close(ctx)
+			}
+		})
+	}
+}
+
+func TestFilterRunTimeoutInterruptsTick(t *testing.T) {
+	// go_tick spins forever, so only wazero closing the module on the run
+	// deadline ends it.
+	spin := slices.Concat([]byte{0x03, 0x40, 0x0c, 0x00, 0x0b}, i32Const(int32(LoopIdle)))
+	ctx := context.Background()
+	rt := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().WithCloseOnContextDone(true))
+	defer rt.Close(ctx)
+	compiled, err := Compile(ctx, rt, reactorModule(spin).encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &Config{MaxRunDuration: 50 * time.Millisecond}
+	outcome, err := Filter(ctx, compiled, strings.NewReader(""), io.Discard, cfg)
+	if !errors.Is(err, ErrRunTimeout) {
+		t.Errorf("got %v, want ErrRunTimeout", err)
+	}
+	if outcome.Exited {
+		t.Errorf("interruption reported as an exit with code %d", outcome.ExitCode)
+	}
+}
+
+func TestRunMaxTicks(t *testing.T) {
+	m := reactorModule(countTick(i32Const(int32(LoopReady)), LoopReady))
+	m.globals = 1
+	r := newTestReactor(t, m, &Config{MaxTicks: 5})
+	if err := r.Run(context.Background()); !errors.Is(err, ErrTickLimitExceeded) {
+		t.Fatalf("got %v, want ErrTickLimitExceeded", err)
+	}
+	if n := global(r, 0); n != 5 {
+		t.Errorf("guest ticked %d times, want 5", n)
+	}
+}
+
+func TestRunCancelledContext(t *testing.T) {
+	m := reactorModule(countTick(i32Const(int32(LoopIdle)), LoopIdle))
+	m.globals = 1
+	r := newTestReactor(t, m, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := r.Run(ctx); err != context.Canceled {
+		t.Fatalf("got %v, want context.Canceled", err)
+	}
+	if n := global(r, 0); n != 0 {
+		t.Errorf("guest ticked %d times, want 0", n)
+	}
+}
+
+func TestNewReactorStandaloneClose(t *testing.T) {
+	ctx := context.Background()
+	wasm := reactorModule(i32Const(int32(LoopIdle))).encode()
+	r, err := NewReactorStandalone(ctx, wasm, nil)
+	if err != nil {
+		t.Fatalf("new reactor: %v", err)
+	}
+	if err := r.Close(ctx); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	if _, err := r.runtime.CompileModule(ctx, wasm); err == nil {
+		t.Error("runtime still compiles after Close")
+	}
+}
+
+func TestRunMaxRunDuration(t *testing.T) {
+	r := newTestReactor(t, reactorModule(i32Const(int32(LoopReady))), &Config{MaxRunDuration: 20 * time.Millisecond})
+	start := time.Now()
+	if err := r.Run(context.Background()); !errors.Is(err, ErrRunTimeout) {
+		t.Fatalf("got %v, want ErrRunTimeout", err)
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("run took %v", d)
+	}
+}
+
+func TestNewReactorInitTimeout(t *testing.T) {
+	m := reactorModule(i32Const(int32(LoopIdle)))
+	m.funcs[0].body = []byte{0x03, 0x40, 0x0c, 0x00, 0x0b} // loop; br 0; end
+	ctx := context.Background()
+	rt := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().WithCloseOnContextDone(true))
+	defer rt.Close(ctx)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := NewReactor(ctx, rt, m.encode(), &Config{InitTimeout: 20 * time.Millisecond})
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrInitTimeout) {
+			t.Errorf("got %v, want ErrInitTimeout", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("NewReactor did not return after InitTimeout")
+	}
+}
+
+// newEchoReactor returns a reactor whose guest copies stdin to stdout, up to
+// 16 bytes a tick, and waits for Notify once a read fails or returns nothing.
+func newEchoReactor(t *testing.T, cfg *Config) *Reactor {
+	t.Helper()
+	tick := slices.Concat(
+		[]byte{0x02, 0x7f}, // block (result i32)
+		i32Const(int32(LoopWaitForever)),
+		i32Const(0), i32Const(0), i32Const(1), i32Const(16), callFunc(0), // fd_read
+		[]byte{0x0d, 0x00, 0x1a}, // br_if 0; drop
+		i32Const(int32(LoopWaitForever)),
+		i32Const(16), []byte{0x28, 0x02, 0x00, 0x45, 0x0d, 0x00, 0x1a}, // br_if 0 (nread == 0); drop
+		i32Const(28), i32Const(16), []byte{0x28, 0x02, 0x00, 0x36, 0x02, 0x00}, // write iovec length = nread
+		i32Const(1), i32Const(24), i32Const(1), i32Const(32), callFunc(1), []byte{0x1a}, // fd_write
+		i32Const(int32(LoopReady)),
+		[]byte{0x0b},
+	)
+	m := reactorModule(tick)
+	m.imports = []testImport{fdRead, fdWrite}
+	r := newTestReactor(t, m, cfg)
+	writeMem(t, r, 0, iovec(64, 16))
+	writeMem(t, r, 24, iovec(64, 0))
+	return r
+}
+
+func TestRunStopOnStdinEOF(t *testing.T) {
+	const input = "echo everything until the end of the input"
+	var out bytes.Buffer
+	r := newEchoReactor(t, &Config{
+		Stdin:          strings.NewReader(input),
+		Stdout:         &out,
+		StopOnStdinEOF: true,
+	})
+	waitRun(t, runAsync(r))
+	if out.String() != input {
+		t.Errorf("echoed %q, want %q", out.String(), input)
+	}
+}
+
+func TestExitFlushesOutput(t *testing.T) {
+	// go_tick writes a line to stdout and exits at once
+	tick := slices.Concat(
+		i32Const(1), i32Const(0), i32Const(1), i32Const(16), callFunc(0), []byte{0x1a},
+		i32Const(0), callFunc(1),
+		i32Const(int32(LoopIdle)),
+	)
+	m := reactorModule(tick)
+	m.imports = []testImport{fdWrite, procExit}
+	var out bytes.Buffer
+	r := newTestReactor(t, m, &Config{Stdout: bufio.NewWriter(&out)})
+	writeMem(t, r, 0, iovec(64, 10))
+	writeMem(t, r, 64, []byte("last line\n"))
+	var exitErr *sys.ExitError
+	if err := r.Run(context.Background()); !errors.As(err, &exitErr) || exitErr.ExitCode() != 0 {
+		t.Fatalf("got %v, want exit code 0", err)
+	}
+	if out.String() != "last line\n" {
+		t.Errorf("output %q, want %q", out.String(), "last line\n")
+	}
+}
+
+func TestWorkDir(t *testing.T) {
+	environ := func(name string) testImport {
+		return testImport{
+			module:  "wasi_snapshot_preview1",
+			name:    name,
+			params:  []api.ValueType{api.ValueTypeI32, api.ValueTypeI32},
+			results: []api.ValueType{api.ValueTypeI32},
+		}
+	}
+	// The first tick reads the environment, as the Go runtime does at
+	// startup; the second opens the path at 64, whose length is at 32,
+	// keeping the errno in g1
+	tick := slices.Concat(
+		[]byte{0x23, 0x00, 0x41, 0x01, 0x6a, 0x24, 0x00}, // g0++
+		[]byte{0x23, 0x00, 0x41, 0x01, 0x46, 0x04, 0x7f}, // if g0 == 1 (result i32)
+		i32Const(0), i32Const(4), callFunc(1), []byte{0x1a}, // environ_sizes_get
+		i32Const(128), i32Const(256), callFunc(2), []byte{0x1a}, // environ_get
+		i32Const(int32(LoopReady)),
+		[]byte{0x05}, // else
+		openFile(slices.Concat(i32Const(32), []byte{0x28, 0x02, 0x00})),
+		[]byte{0x24, 0x01}, // g1 = errno
+		i32Const(int32(LoopIdle)),
+		[]byte{0x0b},
+	)
+	m := reactorModule(tick)
+	m.imports = []testImport{pathOpen, environ("environ_sizes_get"), environ("environ_get")}
+	m.globals = 2
+	r := newTestReactor(t, m, &Config{
+		Mounts:  []Mount{{GuestPath: "/", FS: fstest.MapFS{"data/f.txt": {Data: []byte("x")}}}},
+		Env:     []string{"PWD=/elsewhere"},
+		WorkDir: "/data",
+	})
+	ctx := context.Background()
+	if err := r.StartMain(ctx); err != nil {
+		t.Fatalf("start main: %v", err)
+	}
+	if _, err := r.LoopOnce(ctx); err != nil {
+		t.Fatalf("tick: %v", err)
+	}
+
+	mem := r.Module().Memory()
+	size, _ := mem.ReadUint32Le(4)
+	env, _ := mem.Read(256, size)
+	var pwd string
+	for _, kv := range strings.Split(strings.TrimSuffix(string(env), "\x00"), "\x00") {
+		if v, ok := strings.CutPrefix(kv, "PWD="); ok {
+			if pwd != "" {
+				t.Errorf("PWD set twice, to %q and %q", pwd, v)
+			}
+			pwd = v
+		}
+	}
+	if pwd != "/data" {
+		t.Fatalf("guest PWD = %q, want /data", pwd)
+	}
+
+	// Resolve a relative path against PWD as the guest's os package would,
+	// then open it relative to the root preopen
+	rel := strings.TrimPrefix(path.Join(pwd, "f.txt"), "/")
+	writeMem(t, r, 32, binary.LittleEndian.AppendUint32(nil, uint32(len(rel))))
+	writeMem(t, r, 64, []byte(rel))
+	if _, err := r.LoopOnce(ctx); err != nil {
+		t.Fatalf("tick: %v", err)
+	}
+	if errno := global(r, 1); errno != 0 {
+		t.Errorf("open %s failed with errno %d", rel, errno)
+	}
+}
+
+func TestClockResolutionSleep(t *testing.T) {
+	// go_tick sleeps 10ms as the Go runtime would: it reads the monotonic
+	// clock into 0, sets a timer due 10ms after the first reading at 8, and
+	// returns the milliseconds left, rounded up, or LoopIdle once it is due
+	tick := slices.Concat(
+		i32Const(1), i64Const(0), i32Const(0), callFunc(0), []byte{0x1a},
+		i32Const(8), []byte{0x29, 0x03, 0x00, 0x50, 0x04, 0x40}, // if due == 0
+		i32Const(8), i32Const(0), []byte{0x29, 0x03, 0x00},
+		i64Const(int64(10*time.Millisecond)), []byte{0x7c, 0x37, 0x03, 0x00}, // due = now + 10ms
+		[]byte{0x0b},
+		i32Const(8), []byte{0x29, 0x03, 0x00}, i32Const(0), []byte{0x29, 0x03, 0x00, 0x7d}, // due - now
+		i64Const(int64(time.Millisecond-1)), []byte{0x7c},
+		i64Const(int64(time.Millisecond)), []byte{0x7f, 0xa7, 0x24, 0x00}, // g0 = ceil(ms)
+		[]byte{0x23, 0x00}, i32Const(0), []byte{0x4a, 0x04, 0x7f}, // if g0 > 0 (result i32)
+		[]byte{0x23, 0x00},
+		[]byte{0x05}, // else
+		i32Const(int32(LoopIdle)),
+		[]byte{0x0b},
+	)
+	m := reactorModule(tick)
+	m.imports = []testImport{clockTimeGet}
+	m.globals = 1
+	r := newTestReactor(t, m, &Config{ClockResolution: time.Millisecond})
+	ctx := context.Background()
+	if err := r.StartMain(ctx); err != nil {
+		t.Fatalf("start main: %v", err)
+	}
+
+	start := time.Now()
+	result, err := r.LoopOnce(ctx)
+	if err != nil {
+		t.Fatalf("tick: %v", err)
+	}
+	if result < 9 || result > 10 {
+		t.Errorf("10ms sleep gave LoopResult %v, want about 10", result)
+	}
+	waitRun(t, runAsync(r))
+	if d := time.Since(start); d < 9*time.Millisecond {
+		t.Errorf("sleep ended after %v, want about 10ms", d)
+	}
+}
+
+func TestWarnOnImmediateIdleAfterManualStart(t *testing.T) {
+	m := reactorModule(countTick(i32Const(int32(LoopReady)), LoopIdle))
+	m.globals = 1
+	var logs bytes.Buffer
+	r := newTestReactor(t, m, &Config{
+		ManualStart:         true,
+		WarnOnImmediateIdle: true,
+		Logger:              slog.New(slog.NewTextHandler(&logs, nil)),
+	})
+	ctx := context.Background()
+	// A tick before main is queued, as when driving exports first
+	if _, err := r.LoopOnce(ctx); err != nil {
+		t.Fatalf("tick: %v", err)
+	}
+	if err := r.StartMain(ctx); err != nil {
+		t.Fatalf("start main: %v", err)
+	}
+	if result, err := r.LoopOnce(ctx); err != nil || result != LoopIdle {
+		t.Fatalf("tick = %v, %v; want idle", result, err)
+	}
+	if !strings.Contains(logs.String(), "first tick after start_main reported idle") {
+		t.Errorf("no warning logged; logs: %q", logs.String())
+	}
+}
@@ -0,0 +1,78 @@
+package reactortest
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	reactor "github.com/user/golang-reactor/wazero-go"
+)
+
+// GoldenEpoch is the wall time a RunGolden guest starts at unless
+// GoldenOptions.Start is set.
+var GoldenEpoch = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// GoldenOptions configures RunGolden. The zero value runs the guest with no
+// arguments and an empty environment.
+type GoldenOptions struct {
+	// Args are the guest's arguments, including the program name.
+	Args []string
+	// Env are the guest's environment variables as KEY=value.
+	Env []string
+	// Start is the guest's wall time when it starts. Defaults to GoldenEpoch.
+	Start time.Time
+	// Random, if set, is the data random_get returns, as for
+	// Config.ReplayRandom. Otherwise wazero's default source is used, which
+	// is already deterministic.
+	Random []byte
+	// SchedulerSeed seeds the guest scheduler. Defaults to 1.
+	SchedulerSeed uint64
+	// Config, if set, provides other settings. Its stdio, clock, random, and
+	// scheduler settings are replaced.
+	Config *reactor.Config
+}
+
+// RunGolden runs compiled to completion with input as stdin and everything the
+// guest can observe fixed: arguments, environment, a FastForwardClock starting
+// at a fixed time, random data, and goroutine scheduling. It returns the
+// guest's stdout, for comparing against a golden file; the same guest and
+// input give the same output on every run.
+//
+// The test fails if the guest cannot be run or does not exit with status
+// zero, with its stderr in the failure message.
+func RunGolden(t testing.TB, compiled *reactor.CompiledReactor, input []byte, opts *GoldenOptions) []byte {
+	t.Helper()
+
+	if opts == nil {
+		opts = &GoldenOptions{}
+	}
+	var cfg reactor.Config
+	if opts.Config != nil {
+		cfg = *opts.Config
+	}
+	start := opts.Start
+	if start.IsZero() {
+		start = GoldenEpoch
+	}
+	cfg.Args = opts.Args
+	cfg.Env = opts.Env
+	cfg.Clock = reactor.NewFastForwardClock(start)
+	cfg.ReplayRandom = opts.Random
+	cfg.SchedulerSeed = opts.SchedulerSeed
+	if cfg.SchedulerSeed == 0 {
+		cfg.SchedulerSeed = 1
+	}
+	var stderr bytes.Buffer
+	cfg.Stderr = &stderr
+
+	var stdout bytes.Buffer
+	outcome, err := reactor.Filter(context.Background(), compiled, bytes.NewReader(input), &stdout, &cfg)
+	if err != nil {
+		t.Fatalf("run reactor: %v\nstderr:\n%s", err, stderr.Bytes())
+	}
+	if outcome.Exited && outcome.ExitCode != 0 {
+		t.Fatalf("guest exited with status %d\nstderr:\n%s", outcome.ExitCode, stderr.Bytes())
+	}
+	return stdout.Bytes()
+}
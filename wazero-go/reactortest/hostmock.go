@@ -0,0 +1,99 @@
+package reactortest
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/tetratelabs/wazero/api"
+	reactor "github.com/user/golang-reactor/wazero-go"
+)
+
+// HostImportMock fakes host imports for testing guests that depend on them.
+// Register each import with Func, program its results with Return, pass
+// HostModules as Config.HostModules, and check the guest's calls afterwards
+// with Calls or AssertCalled. Imports are named "module.name", e.g.
+// "env.host_log". It is safe for concurrent use.
+type HostImportMock struct {
+	tb testing.TB
+
+	mu      sync.Mutex
+	mods    []reactor.HostModule
+	calls   map[string][][]uint64
+	results map[string][]uint64
+}
+
+// NewHostImportMock returns a mock with no imports, reporting assertion
+// failures to tb.
+func NewHostImportMock(tb testing.TB) *HostImportMock {
+	return &HostImportMock{
+		tb:      tb,
+		calls:   make(map[string][][]uint64),
+		results: make(map[string][]uint64),
+	}
+}
+
+// Func registers the import name, e.g. "env.host_log", with the given wasm
+// signature. Until Return is called it returns zeros. Func must be called
+// before HostModules.
+func (m *HostImportMock) Func(name string, params, results []api.ValueType) {
+	module, fn, ok := strings.Cut(name, ".")
+	if !ok {
+		m.tb.Fatalf("host import %q is not of the form module.name", name)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.mods = append(m.mods, reactor.HostModule{
+		Name: module,
+		Functions: []reactor.HostFunction{{
+			Name:    fn,
+			Params:  params,
+			Results: results,
+			Func: func(_ context.Context, _ api.Module, stack []uint64) {
+				m.record(name, stack[:len(params)], stack[:len(results)])
+			},
+		}},
+	})
+}
+
+// record logs a call to name with args, then writes its programmed results.
+func (m *HostImportMock) record(name string, args, results []uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.calls[name] = append(m.calls[name], append([]uint64(nil), args...))
+	clear(results)
+	copy(results, m.results[name])
+}
+
+// Return programs the raw results the import name returns from now on, e.g.
+// api.EncodeI32(0). Missing results are zero.
+func (m *HostImportMock) Return(name string, results ...uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.results[name] = results
+}
+
+// HostModules returns the registered imports, for Config.HostModules.
+func (m *HostImportMock) HostModules() []reactor.HostModule {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]reactor.HostModule(nil), m.mods...)
+}
+
+// Calls returns the raw arguments of each call to the import name so far, in
+// order.
+func (m *HostImportMock) Calls(name string) [][]uint64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([][]uint64(nil), m.calls[name]...)
+}
+
+// AssertCalled reports a test error unless the import name was called exactly
+// times times.
+func (m *HostImportMock) AssertCalled(name string, times int) {
+	m.tb.Helper()
+	if n := len(m.Calls(name)); n != times {
+		m.tb.Errorf("host import %s called %d times, want %d", name, n, times)
+	}
+}
@@ -0,0 +1,166 @@
+package reactor
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// MaxRecordSize is the largest record accepted on Config.RecordFD. A frame
+// announcing a longer record fails the guest's write, as it most likely means
+// the stream is not framed as expected.
+const MaxRecordSize = 64 << 20
+
+// recordBacklog is how many decoded records Reactor.Records buffers before
+// guest writes block.
+const recordBacklog = 64
+
+// recordWriter decodes the guest's writes to Config.RecordFD into records.
+// Each record is framed as a little-endian uint32 length followed by that many
+// bytes; frames may be split across writes, and so across ticks.
+type recordWriter struct {
+	ch   chan []byte
+	done chan struct{}
+	buf  []byte
+	once sync.Once
+
+	// mu is held by Write, so close can wait for a write in progress to
+	// let go of ch before closing it.
+	mu     sync.Mutex
+	closed bool
+}
+
+// newRecordWriter returns a recordWriter with an open channel.
+func newRecordWriter() *recordWriter {
+	return &recordWriter{
+		ch:   make(chan []byte, recordBacklog),
+		done: make(chan struct{}),
+	}
+}
+
+// Write implements io.Writer, sending each completed record to ch. Once the
+// writer is closed it fails with io.ErrClosedPipe, including a write blocked
+// on a full backlog.
+func (w *recordWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return 0, io.ErrClosedPipe
+	}
+	w.buf = append(w.buf, p...)
+	for len(w.buf) >= 4 {
+		n := binary.LittleEndian.Uint32(w.buf)
+		if n > MaxRecordSize {
+			w.buf = nil
+			return 0, fmt.Errorf("record of %d bytes exceeds MaxRecordSize", n)
+		}
+		if uint64(len(w.buf)-4) < uint64(n) {
+			break
+		}
+		select {
+		case w.ch <- append([]byte(nil), w.buf[4:4+n]...):
+		case <-w.done:
+			return 0, io.ErrClosedPipe
+		}
+		w.buf = w.buf[4+n:]
+	}
+	if len(w.buf) == 0 {
+		w.buf = nil
+	}
+	return len(p), nil
+}
+
+// close closes the channel, dropping any incomplete record. It is safe to
+// call while the guest is writing: a blocked write is released first, and the
+// channel is closed only once no write can send on it.
+func (w *recordWriter) close() {
+	w.once.Do(func() {
+		close(w.done)
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		w.closed = true
+		w.buf = nil
+		close(w.ch)
+	})
+}
+
+// Records returns the records the guest writes to Config.RecordFD, in order,
+// or nil if RecordFD is not set. The channel is closed when the guest exits
+// or the reactor is closed; a partially written record is then dropped.
+//
+// Records are buffered up to a small backlog, beyond which the guest's write,
+// and so its tick, blocks until the channel is read. Read it from a different
+// goroutine to the one driving the reactor.
+func (r *Reactor) Records() <-chan []byte {
+	if r.records == nil {
+		return nil
+	}
+	return r.records.ch
+}
+
+// Stream runs the reactor like Run, calling onRecord with each record the
+// guest writes to Config.RecordFD, and returns once the guest goes idle or
+// exits and every record written has been handled. If onRecord returns an
+// error, the run is canceled and that error returned. It returns
+// ErrUnsupported if RecordFD is not set.
+//
+// Records are passed to onRecord one at a time, in the order the guest wrote
+// them, on a goroutine other than the one running the guest, so the guest
+// keeps running while onRecord works; once Records' small backlog is full the
+// guest's writes block until onRecord catches up. A record may be retained by
+// onRecord, as each is a fresh copy.
+func (r *Reactor) Stream(ctx context.Context, onRecord func([]byte) error) error {
+	if r.records == nil {
+		return fmt.Errorf("%w: Config.RecordFD is not set", ErrUnsupported)
+	}
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	stop := make(chan struct{})
+	done := make(chan error, 1)
+	go func() {
+		// After onRecord fails, keep draining so a guest blocked writing a
+		// record can see the cancellation
+		var err error
+		handle := func(rec []byte) {
+			if err == nil {
+				if err = onRecord(rec); err != nil {
+					cancel(err)
+				}
+			}
+		}
+		for {
+			select {
+			case rec, ok := <-r.records.ch:
+				if !ok {
+					done <- err
+					return
+				}
+				handle(rec)
+			case <-stop:
+				// The run is over; handle what it left buffered
+				for {
+					select {
+					case rec, ok := <-r.records.ch:
+						if ok {
+							handle(rec)
+							continue
+						}
+					default:
+					}
+					done <- err
+					return
+				}
+			}
+		}
+	}()
+
+	err := r.Run(ctx)
+	close(stop)
+	if recErr := <-done; recErr != nil {
+		return recErr
+	}
+	return err
+}
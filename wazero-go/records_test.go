@@ -0,0 +1,97 @@
+package reactor
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+)
+
+// writeRecord returns instructions writing the iovec at iov to stdout and
+// dropping the errno.
+func writeRecord(iov int32) []byte {
+	return slices.Concat(i32Const(1), i32Const(iov), i32Const(1), i32Const(16), callFunc(0), []byte{0x1a})
+}
+
+func TestRecordsCloseWhileWriting(t *testing.T) {
+	// go_tick writes one-byte records until a write fails
+	tick := slices.Concat(
+		[]byte{0x02, 0x40, 0x03, 0x40}, // block; loop
+		i32Const(1), i32Const(0), i32Const(1), i32Const(16), callFunc(0),
+		[]byte{0x0d, 0x01, 0x0c, 0x00, 0x0b, 0x0b}, // br_if 1; br 0; end; end
+		i32Const(int32(LoopIdle)),
+	)
+	m := reactorModule(tick)
+	m.imports = []testImport{fdWrite}
+	r := newTestReactor(t, m, &Config{RecordFD: 1})
+	writeMem(t, r, 0, iovec(64, 5))
+	writeMem(t, r, 64, []byte("\x01\x00\x00\x00x"))
+	ctx := context.Background()
+	if err := r.StartMain(ctx); err != nil {
+		t.Fatalf("start main: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := r.LoopOnce(ctx)
+		done <- err
+	}()
+	for deadline := time.Now().Add(5 * time.Second); len(r.Records()) < recordBacklog; {
+		if time.Now().After(deadline) {
+			t.Fatal("guest did not fill the record backlog")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if err := r.Close(ctx); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("tick blocked writing a record: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("tick blocked writing a record did not return after Close")
+	}
+
+	var n int
+	for range r.Records() {
+		n++
+	}
+	if n != recordBacklog {
+		t.Errorf("drained %d records, want %d", n, recordBacklog)
+	}
+}
+
+func TestRecordsSplitAcrossTicks(t *testing.T) {
+	// The first tick writes the length prefix and one byte, the second the
+	// rest of the record
+	tick := slices.Concat(
+		[]byte{0x23, 0x00, 0x41, 0x01, 0x6a, 0x24, 0x00}, // g0++
+		[]byte{0x23, 0x00, 0x41, 0x01, 0x46, 0x04, 0x7f}, // if g0 == 1 (result i32)
+		writeRecord(0), i32Const(int32(LoopReady)),
+		[]byte{0x05}, // else
+		writeRecord(8), i32Const(int32(LoopIdle)),
+		[]byte{0x0b},
+	)
+	m := reactorModule(tick)
+	m.imports = []testImport{fdWrite}
+	m.globals = 1
+	r := newTestReactor(t, m, &Config{RecordFD: 1})
+	writeMem(t, r, 0, slices.Concat(iovec(64, 5), iovec(69, 4)))
+	writeMem(t, r, 64, []byte("\x05\x00\x00\x00hello"))
+
+	if err := r.Run(context.Background()); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if n := r.Ticks(); n != 2 {
+		t.Errorf("ticks = %d, want 2", n)
+	}
+	var got []string
+	for len(r.Records()) > 0 {
+		got = append(got, string(<-r.Records()))
+	}
+	if !slices.Equal(got, []string{"hello"}) {
+		t.Errorf("got records %q, want [\"hello\"]", got)
+	}
+}
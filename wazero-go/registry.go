@@ -0,0 +1,74 @@
+package reactor
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// Registry tracks live reactors so a host can operate on all of them at once,
+// e.g. closing every reactor on shutdown.
+//
+// Reactors join a registry via Config.Registry and leave it when closed.
+type Registry struct {
+	mu       sync.Mutex
+	reactors map[*Reactor]struct{}
+}
+
+// NewRegistry constructs an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{reactors: make(map[*Reactor]struct{})}
+}
+
+// Len returns the number of live reactors in the registry.
+func (g *Registry) Len() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return len(g.reactors)
+}
+
+// ForEach calls fn for each live reactor until fn returns false.
+// fn is called without holding the registry lock, so it may close reactors.
+func (g *Registry) ForEach(fn func(r *Reactor) bool) {
+	for _, r := range g.snapshot() {
+		if !fn(r) {
+			return
+		}
+	}
+}
+
+// CloseAll closes every live reactor, returning any errors joined together.
+func (g *Registry) CloseAll(ctx context.Context) error {
+	var errs []error
+	for _, r := range g.snapshot() {
+		if err := r.Close(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// snapshot returns the live reactors.
+func (g *Registry) snapshot() []*Reactor {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	out := make([]*Reactor, 0, len(g.reactors))
+	for r := range g.reactors {
+		out = append(out, r)
+	}
+	return out
+}
+
+// add registers a reactor.
+func (g *Registry) add(r *Reactor) {
+	g.mu.Lock()
+	g.reactors[r] = struct{}{}
+	g.mu.Unlock()
+}
+
+// remove unregisters a reactor.
+func (g *Registry) remove(r *Reactor) {
+	g.mu.Lock()
+	delete(g.reactors, r)
+	g.mu.Unlock()
+}
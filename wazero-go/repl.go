@@ -0,0 +1,81 @@
+package reactor
+
+import (
+	"bufio"
+	"context"
+	"io"
+)
+
+// RunREPL runs an interactive guest one line at a time: each line read from
+// in is given to the guest's stdin, and the guest runs until it asks for the
+// next line or goes idle, its responses being written to out. It instantiates
+// compiled, runs the session until in is exhausted and the guest is done, and
+// closes the reactor. Other settings are taken from cfg.
+//
+// Lines are read from in only when the guest reads stdin and has consumed the
+// previous line, so a guest is never handed input before it has finished
+// responding. Before each line is read, out is flushed if it is a Flusher, so
+// the response, or a prompt, is visible while the user types. A line the guest
+// answers with no output is simply followed by the next one. Once in is
+// exhausted the guest reads EOF.
+//
+// As with Filter, a guest exiting via proc_exit, including mid-session, is
+// reported in the outcome rather than as an error; any remaining input is
+// left unread.
+//
+// RunREPL takes the compiled module rather than an existing Reactor because
+// it must own the guest's stdin and stdout: a reactor's streams are fixed
+// when it is instantiated, and only a stdin that reads from in on demand
+// lets the driver know when the guest wants the next line.
+func RunREPL(ctx context.Context, compiled *CompiledReactor, in io.Reader, out io.Writer, cfg *Config) (RunOutcome, error) {
+	var c Config
+	if cfg != nil {
+		c = *cfg
+	}
+	c.Stdin, c.StdinFunc = &replInput{in: bufio.NewReader(in), out: out}, nil
+	c.Stdout = out
+
+	r, err := compiled.Instantiate(ctx, &c)
+	if err != nil {
+		return RunOutcome{}, err
+	}
+	defer r.Close(ctx)
+
+	err = r.Run(ctx)
+	outcome := r.Outcome()
+	if outcome.Exited {
+		err = nil
+	}
+	return outcome, err
+}
+
+// replInput is the guest's stdin in RunREPL, reading a line from in each time
+// the previous one is used up.
+type replInput struct {
+	in   *bufio.Reader
+	out  io.Writer
+	line []byte
+	err  error
+}
+
+// Read implements io.Reader.
+func (p *replInput) Read(b []byte) (int, error) {
+	if len(p.line) == 0 {
+		if p.err != nil {
+			return 0, p.err
+		}
+		if f, ok := p.out.(Flusher); ok {
+			if err := f.Flush(); err != nil {
+				return 0, err
+			}
+		}
+		line, err := p.in.ReadBytes('\n')
+		p.line, p.err = line, err
+		if len(line) == 0 {
+			return 0, err
+		}
+	}
+	n := copy(b, p.line)
+	p.line = p.line[n:]
+	return n, nil
+}
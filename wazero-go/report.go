@@ -0,0 +1,87 @@
+package reactor
+
+import (
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+// RunReport summarizes a reactor's run for logging and telemetry.
+type RunReport struct {
+	// Outcome is how the guest finished, if it exited or trapped.
+	Outcome RunOutcome
+	// Ticks is the number of go_tick calls so far.
+	Ticks uint64
+	// Duration is the wall time of the last Run, RunWithCallback, or Serve
+	// call, or zero if none has returned.
+	Duration time.Duration
+	// BytesIn is the number of bytes the guest read from stdin. Zero unless
+	// Config.CountIO is set.
+	BytesIn int64
+	// BytesOut is the number of bytes the guest wrote to stdout and stderr.
+	// Zero unless Config.CountIO is set.
+	BytesOut int64
+	// PeakMemoryPages is the largest size of the guest's linear memory in
+	// 64KiB pages, as reported by Reactor.PeakMemoryPages.
+	PeakMemoryPages uint32
+	// Err is the error returned by the last run, if any.
+	Err error
+}
+
+// lastRun describes a completed run for Report.
+type lastRun struct {
+	done     bool
+	duration time.Duration
+	err      error
+}
+
+// Report returns a summary of the reactor's run so far, typically called once
+// Run returns.
+func (r *Reactor) Report() RunReport {
+	r.runMu.Lock()
+	last := r.lastRun
+	r.runMu.Unlock()
+
+	rep := RunReport{
+		Outcome:         r.Outcome(),
+		Ticks:           r.Ticks(),
+		PeakMemoryPages: r.PeakMemoryPages(),
+	}
+	if last.done {
+		rep.Duration = last.duration
+		rep.Err = last.err
+	}
+	if r.bytesIn != nil {
+		rep.BytesIn = r.bytesIn.Load()
+		rep.BytesOut = r.bytesOut.Load()
+	}
+	return rep
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	r io.Reader
+	n *atomic.Int64
+}
+
+// Read implements io.Reader.
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n.Add(int64(n))
+	return n, err
+}
+
+// eofReader records when the underlying reader returns io.EOF.
+type eofReader struct {
+	r   io.Reader
+	eof *atomic.Bool
+}
+
+// Read implements io.Reader.
+func (e *eofReader) Read(p []byte) (int, error) {
+	n, err := e.r.Read(p)
+	if err == io.EOF {
+		e.eof.Store(true)
+	}
+	return n, err
+}
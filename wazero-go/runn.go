@@ -0,0 +1,84 @@
+package reactor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync/atomic"
+)
+
+// RunNResult is the result of RunN.
+type RunNResult struct {
+	// Result is the result of the last tick.
+	Result LoopResult
+	// Ticks is the number of ticks run.
+	Ticks int
+	// Yielded is true if RunN stopped while the guest was still ready,
+	// because it ran n ticks or a tick exceeded Config.MaxOutputPerTick.
+	Yielded bool
+}
+
+// RunN runs at most n ticks and returns without waiting, for hosts that
+// multiplex many reactors on a few workers. Like Run, it calls StartMain first
+// unless Config.ManualStart is set.
+//
+// RunN returns early when the guest goes idle or waits on a timer or Notify,
+// leaving the wait to the caller: Result is then LoopIdle, LoopWaitForever, or
+// the timer delay. Otherwise it yields with Yielded set after n ticks, or
+// sooner after a tick that wrote more than Config.MaxOutputPerTick bytes,
+// whichever comes first.
+func (r *Reactor) RunN(ctx context.Context, n int) (RunNResult, error) {
+	var res RunNResult
+	if !r.cfg.ManualStart {
+		if err := r.StartMain(ctx); err != nil {
+			return res, fmt.Errorf("start main: %w", err)
+		}
+	}
+
+	for res.Ticks < n {
+		if err := ctx.Err(); err != nil {
+			return res, err
+		}
+
+		result, err := r.LoopOnce(ctx)
+		if err != nil {
+			return res, fmt.Errorf("loop once: %w", err)
+		}
+		res.Result = result
+		res.Ticks++
+
+		retick, err := r.afterTick(ctx, result)
+		if err != nil {
+			return res, err
+		}
+		if result != LoopReady && !retick {
+			return res, nil
+		}
+		if r.tickOutput != nil && r.tickOutput.Load() > r.cfg.MaxOutputPerTick {
+			break
+		}
+	}
+	res.Yielded = true
+	return res, nil
+}
+
+// countingWriter counts the bytes written through it.
+type countingWriter struct {
+	w io.Writer
+	n *atomic.Int64
+}
+
+// Write implements io.Writer.
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n.Add(int64(n))
+	return n, err
+}
+
+// Flush implements Flusher if the underlying writer does.
+func (c *countingWriter) Flush() error {
+	if f, ok := c.w.(Flusher); ok {
+		return f.Flush()
+	}
+	return nil
+}
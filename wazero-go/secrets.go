@@ -0,0 +1,76 @@
+package reactor
+
+import (
+	"bytes"
+	"context"
+	"sync"
+
+	"github.com/tetratelabs/wazero/api"
+)
+
+// secretStore holds the secrets not yet delivered to a guest.
+type secretStore struct {
+	mu      sync.Mutex
+	secrets map[string][]byte
+}
+
+// newSecretStore returns the store for Config.Secrets, or nil if unset. The
+// store holds its own copies of the secrets, so zeroing them once delivered
+// leaves cfg intact for other reactors built from it.
+func (cfg *Config) newSecretStore() *secretStore {
+	if cfg.Secrets == nil {
+		return nil
+	}
+	secrets := make(map[string][]byte, len(cfg.Secrets))
+	for name, secret := range cfg.Secrets {
+		secrets[name] = bytes.Clone(secret)
+	}
+	return &secretStore{secrets: secrets}
+}
+
+// take copies the named secret into buf, deleting and zeroing it. It returns
+// the secret's length, without copying if buf is too small, or -1 if there is
+// no such secret.
+func (s *secretStore) take(name string, buf []byte) int32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	secret, ok := s.secrets[name]
+	if !ok {
+		return -1
+	}
+	if len(secret) > len(buf) {
+		return int32(len(secret))
+	}
+	copy(buf, secret)
+	clear(secret)
+	delete(s.secrets, name)
+	return int32(len(secret))
+}
+
+// getSecretFunc implements env.go_get_secret, see Config.Secrets.
+var getSecretFunc = HostFunction{
+	Name:    "go_get_secret",
+	Params:  []api.ValueType{api.ValueTypeI32, api.ValueTypeI32, api.ValueTypeI32, api.ValueTypeI32},
+	Results: []api.ValueType{api.ValueTypeI32},
+	Func: func(ctx context.Context, mod api.Module, stack []uint64) {
+		stack[0] = api.EncodeI32(getSecret(ctx, mod, stack))
+	},
+}
+
+// getSecret implements env.go_get_secret.
+func getSecret(ctx context.Context, mod api.Module, stack []uint64) int32 {
+	r := callerFromContext(ctx)
+	if r == nil || r.secrets == nil {
+		return -1
+	}
+	mem := mod.Memory()
+	name, ok := mem.Read(api.DecodeU32(stack[0]), api.DecodeU32(stack[1]))
+	if !ok {
+		return -1
+	}
+	buf, ok := mem.Read(api.DecodeU32(stack[2]), api.DecodeU32(stack[3]))
+	if !ok {
+		return -1
+	}
+	return r.secrets.take(string(name), buf)
+}
@@ -0,0 +1,177 @@
+package reactor
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
+)
+
+// DefaultSnapshotInterval is the snapshot interval used when Config.Store is
+// set and Config.SnapshotInterval is zero.
+const DefaultSnapshotInterval = time.Second
+
+// ErrBadSnapshot is returned when a snapshot is corrupt or does not match the
+// module it is being restored into.
+var ErrBadSnapshot = errors.New("invalid reactor snapshot")
+
+// snapshotMagic identifies a reactor snapshot, followed by a format version
+// and the SHA-256 of the wasm it was taken from.
+const (
+	snapshotMagic   = "GRSN"
+	snapshotVersion = 2
+)
+
+// Store persists reactor snapshots so a reactor can resume after the host
+// restarts. See Config.Store.
+type Store interface {
+	// Save replaces the stored snapshot. The slice is not retained by the
+	// reactor and may be kept by the Store.
+	Save(snapshot []byte) error
+	// Load returns the last saved snapshot, or an empty slice if none.
+	Load() ([]byte, error)
+}
+
+// Snapshot captures the guest's linear memory and globals. It must not be
+// called while a tick is in progress; Run takes snapshots between ticks when
+// Config.Store is set.
+//
+// A snapshot only covers guest state. Anything the guest has observed or
+// caused outside its memory, such as output already written, files changed
+// through the mounted filesystem, or requests sent by host functions, is not
+// rolled back when the snapshot is restored, and work done after the snapshot
+// was taken is repeated. Guests that need exactly-once effects must make them
+// idempotent or track them on the host side.
+func (r *Reactor) Snapshot() ([]byte, error) {
+	mem := r.mod.Memory()
+	var data []byte
+	if mem != nil {
+		var ok bool
+		data, ok = mem.Read(0, mem.Size())
+		if !ok {
+			return nil, errors.New("read guest memory")
+		}
+	}
+	globals := r.globals()
+
+	buf := make([]byte, 0, len(snapshotMagic)+13+sha256.Size+len(data)+8*len(globals))
+	buf = append(buf, snapshotMagic...)
+	buf = binary.LittleEndian.AppendUint32(buf, snapshotVersion)
+	buf = append(buf, r.compiled.hash[:]...)
+	var started byte
+	if r.started.Load() {
+		started = 1
+	}
+	buf = append(buf, started)
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(len(globals)))
+	for _, g := range globals {
+		buf = binary.LittleEndian.AppendUint64(buf, g.Get())
+	}
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(len(data)))
+	return append(buf, data...), nil
+}
+
+// restore loads a snapshot taken by Snapshot into a freshly instantiated
+// module, in place of _initialize. The snapshot must have been taken from the
+// same wasm, as recorded by its hash.
+//
+// wazero cannot write unexported globals, such as the Go stack pointer, so
+// they are checked instead: each must hold the same value in the fresh module
+// as in the snapshot, which is the case for a Go guest between ticks.
+// Otherwise ErrBadSnapshot is returned.
+func (r *Reactor) restore(snapshot []byte) error {
+	hdr := len(snapshotMagic) + 9 + sha256.Size
+	if len(snapshot) < hdr || string(snapshot[:len(snapshotMagic)]) != snapshotMagic {
+		return fmt.Errorf("%w: bad header", ErrBadSnapshot)
+	}
+	p := snapshot[len(snapshotMagic):]
+	if v := binary.LittleEndian.Uint32(p); v != snapshotVersion {
+		return fmt.Errorf("%w: unsupported version %d", ErrBadSnapshot, v)
+	}
+	if !bytes.Equal(p[4:4+sha256.Size], r.compiled.hash[:]) {
+		return fmt.Errorf("%w: taken from a different module", ErrBadSnapshot)
+	}
+	p = p[4+sha256.Size:]
+	started := p[0] != 0
+	n := binary.LittleEndian.Uint32(p[1:])
+	p = p[5:]
+
+	globals := r.globals()
+	if int(n) != len(globals) || uint64(len(p)) < 8*uint64(n)+4 {
+		return fmt.Errorf("%w: global count mismatch", ErrBadSnapshot)
+	}
+	for i, g := range globals {
+		if g.Get() != binary.LittleEndian.Uint64(p[8*i:]) {
+			return fmt.Errorf("%w: global %d differs", ErrBadSnapshot, i)
+		}
+	}
+	p = p[8*n:]
+
+	size := binary.LittleEndian.Uint32(p)
+	p = p[4:]
+	if uint32(len(p)) != size {
+		return fmt.Errorf("%w: truncated memory", ErrBadSnapshot)
+	}
+	if size > 0 {
+		mem := r.mod.Memory()
+		if mem == nil {
+			return fmt.Errorf("%w: module has no memory", ErrBadSnapshot)
+		}
+		if cur := mem.Size(); cur < size {
+			if _, ok := mem.Grow((size - cur) / wasmPageSize); !ok {
+				return fmt.Errorf("%w: cannot grow memory to %d bytes", ErrBadSnapshot, size)
+			}
+		}
+		if !mem.Write(0, p) {
+			return fmt.Errorf("%w: cannot write memory", ErrBadSnapshot)
+		}
+	}
+
+	if started {
+		r.startedAt = time.Now()
+		r.started.Store(true)
+	}
+	return nil
+}
+
+// globals returns all of the guest's globals, exported or not, by index.
+func (r *Reactor) globals() []api.Global {
+	im, ok := r.mod.(experimental.InternalModule)
+	if !ok {
+		return nil
+	}
+	globals := make([]api.Global, im.NumGlobal())
+	for i := range globals {
+		globals[i] = im.Global(i)
+	}
+	return globals
+}
+
+// maybeSnapshot saves a snapshot to Config.Store if the snapshot interval has
+// elapsed since the last one, or unconditionally if force is set.
+func (r *Reactor) maybeSnapshot(force bool) error {
+	if r.cfg.Store == nil {
+		return nil
+	}
+	interval := r.cfg.SnapshotInterval
+	if interval <= 0 {
+		interval = DefaultSnapshotInterval
+	}
+	if !force && time.Since(r.lastSnapshot) < interval {
+		return nil
+	}
+	snapshot, err := r.Snapshot()
+	if err == nil {
+		err = r.cfg.Store.Save(snapshot)
+	}
+	if err != nil {
+		return fmt.Errorf("save snapshot: %w", err)
+	}
+	r.lastSnapshot = time.Now()
+	return nil
+}
@@ -0,0 +1,96 @@
+package reactor
+
+import (
+	"io"
+	"sync"
+)
+
+// stdinPipe is the guest's stdin when Config.StdinBufferLimit is set: a pipe
+// fed by the host through Reactor.StdinWriter, buffering at most limit bytes.
+type stdinPipe struct {
+	mu    sync.Mutex
+	cond  sync.Cond
+	buf   []byte
+	limit int
+	// eof is set when the writer is closed, closed when the reactor is.
+	eof, closed bool
+}
+
+// newStdinPipe returns an empty pipe buffering up to limit bytes.
+func newStdinPipe(limit int) *stdinPipe {
+	p := &stdinPipe{limit: limit}
+	p.cond.L = &p.mu
+	return p
+}
+
+// Read implements io.Reader for the guest, blocking until data is written or
+// the writer is closed.
+func (p *stdinPipe) Read(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for len(p.buf) == 0 && !p.eof && !p.closed {
+		p.cond.Wait()
+	}
+	if len(p.buf) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(b, p.buf)
+	p.buf = p.buf[n:]
+	p.cond.Broadcast()
+	return n, nil
+}
+
+// Write implements io.Writer for the host, blocking while the buffer is full.
+func (p *stdinPipe) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var n int
+	for n < len(b) {
+		for len(p.buf) >= p.limit && !p.eof && !p.closed {
+			p.cond.Wait()
+		}
+		if p.eof || p.closed {
+			return n, io.ErrClosedPipe
+		}
+		m := min(len(b)-n, p.limit-len(p.buf))
+		p.buf = append(p.buf, b[n:n+m]...)
+		n += m
+		p.cond.Broadcast()
+	}
+	return n, nil
+}
+
+// Close implements io.Closer for the host: the guest reads EOF once it has
+// drained the buffer.
+func (p *stdinPipe) Close() error {
+	p.mu.Lock()
+	p.eof = true
+	p.mu.Unlock()
+	p.cond.Broadcast()
+	return nil
+}
+
+// closeRead unblocks the writer when the reactor closes.
+func (p *stdinPipe) closeRead() {
+	p.mu.Lock()
+	p.closed = true
+	p.buf = nil
+	p.mu.Unlock()
+	p.cond.Broadcast()
+}
+
+// StdinWriter returns the writer feeding the guest's stdin when
+// Config.StdinBufferLimit is set, or nil otherwise. Writes block while the
+// guest has StdinBufferLimit bytes unread, so a host producing input faster
+// than the guest consumes it is slowed down rather than buffering without
+// bound. Close it to have the guest read EOF. Writes fail with
+// io.ErrClosedPipe once the reactor is closed.
+//
+// A guest reading stdin blocks its tick until input is written, so the
+// writer must run on a different goroutine to the run loop.
+func (r *Reactor) StdinWriter() io.WriteCloser {
+	if r.stdinPipe == nil {
+		return nil
+	}
+	return r.stdinPipe
+}
@@ -0,0 +1,47 @@
+package reactor
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestStdinWriterBackpressure(t *testing.T) {
+	var out bytes.Buffer
+	r := newEchoReactor(t, &Config{StdinBufferLimit: 4, Stdout: &out, StopOnStdinEOF: true})
+	w := r.StdinWriter()
+
+	written := make(chan error, 1)
+	go func() {
+		_, err := w.Write([]byte("abcdefgh"))
+		written <- err
+	}()
+	select {
+	case err := <-written:
+		t.Fatalf("write returned %v with the buffer full and the guest not reading", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	r.stdinPipe.mu.Lock()
+	n := len(r.stdinPipe.buf)
+	r.stdinPipe.mu.Unlock()
+	if n != 4 {
+		t.Errorf("buffered %d bytes, want 4", n)
+	}
+
+	done := runAsync(r)
+	select {
+	case err := <-written:
+		if err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("write did not unblock once the guest read")
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	waitRun(t, done)
+	if out.String() != "abcdefgh" {
+		t.Errorf("echoed %q, want %q", out.String(), "abcdefgh")
+	}
+}
@@ -0,0 +1,70 @@
+package reactor
+
+import "strconv"
+
+// String describes the reactor's state for logging, e.g.
+//
+//	Reactor(name=worker-3 started=true closed=false ticks=1024 mem=2MiB last=ready)
+//
+// It reads only atomic state, so it is safe to call concurrently with the run
+// loop.
+func (r *Reactor) String() string {
+	buf := make([]byte, 0, 96)
+	buf = append(buf, "Reactor("...)
+	if r.cfg.Name != "" {
+		buf = append(buf, "name="...)
+		buf = append(buf, r.cfg.Name...)
+		buf = append(buf, ' ')
+	}
+	buf = append(buf, "started="...)
+	buf = strconv.AppendBool(buf, r.started.Load())
+	buf = append(buf, " closed="...)
+	buf = strconv.AppendBool(buf, r.closed.Load())
+	buf = append(buf, " ticks="...)
+	buf = strconv.AppendUint(buf, r.ticks.Load(), 10)
+	buf = append(buf, " mem="...)
+	buf = appendMemSize(buf, uint64(r.memPages.Load())*wasmPageSize)
+	buf = append(buf, " last="...)
+	if r.lastTick.Load() == 0 {
+		buf = append(buf, "none"...)
+	} else {
+		buf = appendLoopResult(buf, LoopResult(r.lastResult.Load()))
+	}
+	buf = append(buf, ')')
+	return string(buf)
+}
+
+// appendMemSize appends a byte count in KiB or MiB.
+func appendMemSize(buf []byte, n uint64) []byte {
+	if n < 1<<20 {
+		buf = strconv.AppendUint(buf, n>>10, 10)
+		return append(buf, "KiB"...)
+	}
+	buf = strconv.AppendFloat(buf, float64(n)/(1<<20), 'f', -1, 64)
+	return append(buf, "MiB"...)
+}
+
+// String describes the result as "idle", "ready", or "timer(Nms)", e.g.
+// "timer(250ms)", for logging. Other negative values, which guests do not
+// return, are shown as "LoopResult(N)".
+func (r LoopResult) String() string {
+	return string(appendLoopResult(make([]byte, 0, 24), r))
+}
+
+// appendLoopResult appends a short description of a LoopResult.
+func appendLoopResult(buf []byte, result LoopResult) []byte {
+	switch {
+	case result == LoopIdle:
+		return append(buf, "idle"...)
+	case result == LoopReady:
+		return append(buf, "ready"...)
+	case result < 0:
+		buf = append(buf, "LoopResult("...)
+		buf = strconv.AppendInt(buf, int64(result), 10)
+		return append(buf, ')')
+	default:
+		buf = append(buf, "timer("...)
+		buf = strconv.AppendInt(buf, int64(result), 10)
+		return append(buf, "ms)"...)
+	}
+}
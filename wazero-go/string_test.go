@@ -0,0 +1,26 @@
+package reactor
+
+import (
+	"math"
+	"testing"
+)
+
+func TestLoopResultString(t *testing.T) {
+	tests := []struct {
+		result LoopResult
+		want   string
+	}{
+		{LoopIdle, "idle"},
+		{LoopReady, "ready"},
+		{1, "timer(1ms)"},
+		{250, "timer(250ms)"},
+		{LoopWaitForever, "timer(2147483647ms)"},
+		{-2, "LoopResult(-2)"},
+		{math.MinInt32, "LoopResult(-2147483648)"},
+	}
+	for _, tt := range tests {
+		if got := tt.result.String(); got != tt.want {
+			t.Errorf("LoopResult(%d).String() = %q, want %q", int32(tt.result), got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package reactor
+
+import (
+	"context"
+	"fmt"
+	"math"
+)
+
+// Value is a Go type that maps to a single wasm value: i32, i64, f32, or f64.
+type Value interface {
+	int32 | uint32 | int64 | uint64 | float32 | float64
+}
+
+// Arg encodes v as a parameter for Call and the typed call helpers, e.g.
+//
+//	n, err := reactor.CallInt32(ctx, r, "add", reactor.Arg[int32](1), reactor.Arg[int32](2))
+func Arg[T Value](v T) uint64 {
+	switch v := any(v).(type) {
+	case int32:
+		return uint64(uint32(v))
+	case float32:
+		return uint64(math.Float32bits(v))
+	case float64:
+		return math.Float64bits(v)
+	}
+	return uint64(v)
+}
+
+// result decodes a single wasm result as T.
+func result[T Value](v uint64) T {
+	var zero T
+	switch any(zero).(type) {
+	case float32:
+		return T(math.Float32frombits(uint32(v)))
+	case float64:
+		return T(math.Float64frombits(v))
+	case int32, uint32:
+		return T(uint32(v))
+	}
+	return T(v)
+}
+
+// CallResult calls the named export like Reactor.Call and decodes its single
+// result as T. It fails if the export does not return exactly one value.
+func CallResult[T Value](ctx context.Context, r *Reactor, name string, params ...uint64) (T, error) {
+	var zero T
+	results, err := r.Call(ctx, name, params...)
+	if err != nil {
+		return zero, err
+	}
+	if len(results) != 1 {
+		return zero, fmt.Errorf("%s returned %d results, want 1", name, len(results))
+	}
+	return result[T](results[0]), nil
+}
+
+// CallInt32 calls an export returning i32. See CallResult.
+func CallInt32(ctx context.Context, r *Reactor, name string, params ...uint64) (int32, error) {
+	return CallResult[int32](ctx, r, name, params...)
+}
+
+// CallInt64 calls an export returning i64. See CallResult.
+func CallInt64(ctx context.Context, r *Reactor, name string, params ...uint64) (int64, error) {
+	return CallResult[int64](ctx, r, name, params...)
+}
+
+// CallFloat32 calls an export returning f32. See CallResult.
+func CallFloat32(ctx context.Context, r *Reactor, name string, params ...uint64) (float32, error) {
+	return CallResult[float32](ctx, r, name, params...)
+}
+
+// CallFloat64 calls an export returning f64. See CallResult.
+func CallFloat64(ctx context.Context, r *Reactor, name string, params ...uint64) (float64, error) {
+	return CallResult[float64](ctx, r, name, params...)
+}
+
+// CallBytes calls an export following the read-result ABI and returns its
+// result. It is the same as Reactor.CallAndReadResult.
+func CallBytes(ctx context.Context, r *Reactor, name string, input []byte) ([]byte, error) {
+	return r.CallAndReadResult(ctx, name, input)
+}
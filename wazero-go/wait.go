@@ -0,0 +1,77 @@
+package reactor
+
+import (
+	"context"
+	"runtime"
+	"time"
+)
+
+// WaitStrategy decides how the run loop waits when the guest reports a pending
+// timer (a positive LoopResult).
+type WaitStrategy interface {
+	// Wait blocks for d. It returns nil early when wake receives, as the host
+	// has queued work for the guest, and ctx.Err() early if ctx is done.
+	Wait(ctx context.Context, d time.Duration, wake <-chan struct{}) error
+}
+
+// TimerWait waits using a time.Timer. It uses no CPU while waiting, but wakeups
+// are subject to the host's timer granularity and scheduling jitter.
+// This is the default strategy.
+type TimerWait struct{}
+
+// Wait implements WaitStrategy.
+func (TimerWait) Wait(ctx context.Context, d time.Duration, wake <-chan struct{}) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-wake:
+		return nil
+	case <-timer.C:
+		return nil
+	}
+}
+
+// SpinWait busy-polls the clock, calling runtime.Gosched between polls.
+// It gives the lowest wakeup latency but keeps a CPU busy for the whole wait.
+type SpinWait struct{}
+
+// Wait implements WaitStrategy.
+func (SpinWait) Wait(ctx context.Context, d time.Duration, wake <-chan struct{}) error {
+	deadline := time.Now().Add(d)
+	for time.Now().Before(deadline) {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-wake:
+			return nil
+		default:
+		}
+		runtime.Gosched()
+	}
+	return nil
+}
+
+// DefaultHybridThreshold is the spin threshold used by a zero HybridWait.
+const DefaultHybridThreshold = 2 * time.Millisecond
+
+// HybridWait spins for waits shorter than Threshold and uses a timer otherwise,
+// trading CPU for latency only on the short waits where timer jitter dominates.
+type HybridWait struct {
+	// Threshold is the longest wait that spins.
+	// Defaults to DefaultHybridThreshold.
+	Threshold time.Duration
+}
+
+// Wait implements WaitStrategy.
+func (h HybridWait) Wait(ctx context.Context, d time.Duration, wake <-chan struct{}) error {
+	threshold := h.Threshold
+	if threshold <= 0 {
+		threshold = DefaultHybridThreshold
+	}
+	if d < threshold {
+		return SpinWait{}.Wait(ctx, d, wake)
+	}
+	return TimerWait{}.Wait(ctx, d, wake)
+}
@@ -0,0 +1,53 @@
+package reactor
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestWaitStrategyWake(t *testing.T) {
+	for _, s := range []WaitStrategy{TimerWait{}, SpinWait{}, HybridWait{}} {
+		wake := make(chan struct{}, 1)
+		wake <- struct{}{}
+		start := time.Now()
+		if err := s.Wait(context.Background(), time.Hour, wake); err != nil {
+			t.Errorf("%T: %v", s, err)
+		}
+		if d := time.Since(start); d > time.Second {
+			t.Errorf("%T: waited %v despite wake", s, d)
+		}
+	}
+}
+
+// BenchmarkWaitStrategy measures how late each strategy wakes from a 1ms
+// wait, reported as mean and worst jitter.
+func BenchmarkWaitStrategy(b *testing.B) {
+	const d = time.Millisecond
+	strategies := []struct {
+		name string
+		s    WaitStrategy
+	}{
+		{"Timer", TimerWait{}},
+		{"Spin", SpinWait{}},
+		{"Hybrid", HybridWait{}},
+	}
+	for _, st := range strategies {
+		b.Run(st.name, func(b *testing.B) {
+			ctx := context.Background()
+			wake := make(chan struct{})
+			var total, worst time.Duration
+			for range b.N {
+				start := time.Now()
+				if err := st.s.Wait(ctx, d, wake); err != nil {
+					b.Fatal(err)
+				}
+				late := time.Since(start) - d
+				total += late
+				worst = max(worst, late)
+			}
+			b.ReportMetric(float64(total.Nanoseconds())/float64(b.N), "jitter-ns/op")
+			b.ReportMetric(float64(worst.Nanoseconds()), "max-jitter-ns")
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package reactor
+
+import (
+	"runtime"
+	"slices"
+)
+
+// compilerPlatform reports whether the host is one wazero's optimizing
+// compiler targets. wazero does not expose which engine a runtime uses, so
+// this copies its platform check, minus its CPU feature detection; keep it in
+// step when upgrading wazero.
+func compilerPlatform() bool {
+	if runtime.GOARCH != "amd64" && runtime.GOARCH != "arm64" {
+		return false
+	}
+	switch runtime.GOOS {
+	case "linux", "darwin", "freebsd", "netbsd", "dragonfly", "windows":
+		return true
+	case "solaris", "illumos":
+		return runtime.GOARCH == "amd64"
+	}
+	return false
+}
+
+// compatibilityWarnings returns notes about running the compiled module on
+// this host that would otherwise only show up as poor performance or
+// confusing failures later.
+func (c *CompiledReactor) compatibilityWarnings() []string {
+	var warnings []string
+	if !compilerPlatform() {
+		warnings = append(warnings, "wazero's compiler does not support "+runtime.GOOS+"/"+runtime.GOARCH+
+			": runtimes with the default configuration use the interpreter, which runs guests many times slower")
+	}
+	if len(c.compiled.ExportedMemories()) == 0 {
+		warnings = append(warnings, "module exports no memory: memory stats, snapshots, and memory reads are unavailable")
+	}
+	return warnings
+}
+
+// Warnings returns compatibility notes about running the module on this host,
+// so problems can be reported up front rather than discovered at the first
+// tick. It returns nil if there are none.
+//
+// The notes come from two static checks made when the module was compiled:
+// whether wazero's compiler supports this platform, and whether the module
+// exports its memory. wazero itself reports no compile-time warnings, nor
+// which engine a runtime uses, so the platform note predicts what a runtime
+// with the default configuration does: it is not given for a runtime
+// configured to use the interpreter on a supported platform, and a CPU
+// lacking features the compiler needs goes unnoticed.
+func (c *CompiledReactor) Warnings() []string {
+	return slices.Clone(c.warnings)
+}
+
+// Warnings returns the warnings of the compiled module the reactor was
+// instantiated from; see CompiledReactor.Warnings.
+func (r *Reactor) Warnings() []string {
+	return r.compiled.Warnings()
+}
@@ -0,0 +1,154 @@
+package reactor
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync/atomic"
+	"time"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
+)
+
+// WASICall describes one call from the guest into a WASI function.
+// See Config.WASITrace.
+type WASICall struct {
+	// Name is the WASI function name, e.g. "fd_write".
+	Name string
+	// Params are the raw parameters the guest passed.
+	Params []uint64
+	// Results are the raw results, usually a single WASI errno. Nil if Err
+	// is set.
+	Results []uint64
+	// Err is set if the call did not return normally, e.g. proc_exit.
+	Err error
+}
+
+// ErrClockLimitExceeded is returned when the guest reads the clock more often
+// than Config.MaxClockCalls allows. The reactor is closed when it is returned.
+var ErrClockLimitExceeded = errors.New("guest exceeded clock call limit")
+
+// wasiHooks receives the WASI calls of one reactor. wasiListener finds them
+// through the reactor attached to the context of each guest call.
+type wasiHooks struct {
+	trace      func(WASICall)
+	onProcExit func(code uint32, at time.Time)
+	// params of the WASI call in progress; WASI functions do not re-enter
+	// the guest, so calls never nest.
+	params []uint64
+	// maxClock is Config.MaxClockCalls; clockCalls counts clock calls.
+	maxClock   uint64
+	clockCalls atomic.Uint64
+	// files, if Config.MaxOpenFiles is set, reports opens refused at the
+	// limit, which after rewrites to EMFILE.
+	files *openFiles
+}
+
+// WASI errnos: EMFILE, which wazero's sys.Errno cannot express, and the
+// EAGAIN countingFS returns in its place.
+const (
+	errnoAgain = 6
+	errnoMfile = 33
+)
+
+// newWASIHooks returns the hooks for cfg, or nil if it needs none.
+func (cfg *Config) newWASIHooks(files *openFiles) *wasiHooks {
+	if cfg.WASITrace == nil && cfg.OnProcExit == nil && cfg.MaxClockCalls == 0 && cfg.MaxOpenFiles <= 0 {
+		return nil
+	}
+	h := &wasiHooks{trace: cfg.WASITrace, onProcExit: cfg.OnProcExit, maxClock: cfg.MaxClockCalls}
+	if cfg.MaxOpenFiles > 0 {
+		h.files = files
+	}
+	return h
+}
+
+func (h *wasiHooks) before(def api.FunctionDefinition, params []uint64) {
+	if name := def.Name(); name == "clock_time_get" || name == "clock_res_get" {
+		// Panicking aborts the guest call, which returns the error
+		if n := h.clockCalls.Add(1); h.maxClock != 0 && n > h.maxClock {
+			panic(ErrClockLimitExceeded)
+		}
+	}
+	if h.onProcExit != nil && def.Name() == "proc_exit" {
+		h.onProcExit(api.DecodeU32(params[0]), time.Now())
+	}
+	if h.trace != nil {
+		h.params = slices.Clone(params)
+	}
+}
+
+func (h *wasiHooks) after(def api.FunctionDefinition, results []uint64, err error) {
+	if h.files != nil && err == nil && def.Name() == "path_open" && h.files.refused.Swap(false) && results[0] == errnoAgain {
+		// countingFS refused the open with the nearest errno wazero can
+		// return; results is the guest's stack, so this changes what the
+		// guest sees
+		results[0] = errnoMfile
+	}
+	if h.trace != nil {
+		h.trace(WASICall{
+			Name:    def.Name(),
+			Params:  h.params,
+			Results: slices.Clone(results),
+			Err:     err,
+		})
+	}
+	h.params = nil
+}
+
+// ClockCallCount returns the number of times the guest has read the clock
+// through WASI clock_time_get or clock_res_get. Calls are only counted when
+// Config.MaxClockCalls, WASITrace, OnProcExit, or MaxOpenFiles is set;
+// otherwise it returns zero.
+func (r *Reactor) ClockCallCount() uint64 {
+	if r.wasiHooks == nil {
+		return 0
+	}
+	return r.wasiHooks.clockCalls.Load()
+}
+
+// withWASIListener installs wasiListener for the WASI module instantiated
+// with the returned context. It is installed on every runtime, since WASI is
+// shared by all reactors on it; reactors without hooks pay only a context
+// lookup per WASI call.
+func withWASIListener(ctx context.Context) context.Context {
+	return experimental.WithFunctionListenerFactory(ctx, wasiListener{})
+}
+
+// wasiListener dispatches WASI calls to the calling reactor's hooks.
+type wasiListener struct{}
+
+// hooks returns the WASI hooks of the reactor making the call, if any.
+func (wasiListener) hooks(ctx context.Context) *wasiHooks {
+	if r := callerFromContext(ctx); r != nil {
+		return r.wasiHooks
+	}
+	return nil
+}
+
+// NewFunctionListener implements experimental.FunctionListenerFactory.
+func (l wasiListener) NewFunctionListener(api.FunctionDefinition) experimental.FunctionListener {
+	return l
+}
+
+// Before implements experimental.FunctionListener.
+func (l wasiListener) Before(ctx context.Context, _ api.Module, def api.FunctionDefinition, params []uint64, _ experimental.StackIterator) {
+	if h := l.hooks(ctx); h != nil {
+		h.before(def, params)
+	}
+}
+
+// After implements experimental.FunctionListener.
+func (l wasiListener) After(ctx context.Context, _ api.Module, def api.FunctionDefinition, results []uint64) {
+	if h := l.hooks(ctx); h != nil {
+		h.after(def, results, nil)
+	}
+}
+
+// Abort implements experimental.FunctionListener.
+func (l wasiListener) Abort(ctx context.Context, _ api.Module, def api.FunctionDefinition, err error) {
+	if h := l.hooks(ctx); h != nil {
+		h.after(def, nil, err)
+	}
+}
@@ -0,0 +1,32 @@
+package reactor
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestMaxClockCalls(t *testing.T) {
+	// go_tick polls the monotonic clock forever
+	tick := slices.Concat(
+		[]byte{0x03, 0x40}, // loop
+		i32Const(1), i64Const(0), i32Const(0), callFunc(0), []byte{0x1a},
+		[]byte{0x0c, 0x00, 0x0b}, // br 0; end
+		i32Const(int32(LoopIdle)),
+	)
+	m := reactorModule(tick)
+	m.imports = []testImport{clockTimeGet}
+	r := newTestReactor(t, m, &Config{MaxClockCalls: 10})
+
+	if err := r.Run(context.Background()); !errors.Is(err, ErrClockLimitExceeded) {
+		t.Fatalf("got %v, want ErrClockLimitExceeded", err)
+	}
+	// The call over the limit is counted before it is refused
+	if n := r.ClockCallCount(); n != 11 {
+		t.Errorf("ClockCallCount = %d, want 11", n)
+	}
+	if !r.IsClosed() {
+		t.Error("reactor not closed after exceeding the clock limit")
+	}
+}
@@ -0,0 +1,53 @@
+package reactor
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrTickWatchdog is the cancellation cause of a tick stopped by
+// Config.TickWatchdog with WatchdogCancel.
+var ErrTickWatchdog = errors.New("tick exceeded watchdog duration")
+
+// WatchdogAction is what Config.TickWatchdog does when a tick overruns.
+type WatchdogAction int
+
+const (
+	// WatchdogWarn logs a warning and lets the tick continue.
+	WatchdogWarn WatchdogAction = iota
+	// WatchdogCancel logs a warning, cancels the tick's context with
+	// ErrTickWatchdog, and cancels the run in progress as if by
+	// Cancel(CancelTimeout).
+	WatchdogCancel
+)
+
+// startWatchdog arms Config.TickWatchdog for a tick, returning the context to
+// run the tick with and a func to disarm the watchdog once it returns.
+func (r *Reactor) startWatchdog(ctx context.Context) (context.Context, func()) {
+	d := r.cfg.TickWatchdog
+	if d <= 0 {
+		return ctx, func() {}
+	}
+	var cancel context.CancelCauseFunc
+	if r.cfg.TickWatchdogAction == WatchdogCancel {
+		ctx, cancel = context.WithCancelCause(ctx)
+	}
+	start := time.Now()
+	timer := time.AfterFunc(d, func() {
+		// The guest's stack cannot be read while it runs, so report what the
+		// host knows.
+		r.logger().Warn("tick exceeded watchdog duration",
+			"reactor", r.cfg.Name, "watchdog", d, "elapsed", time.Since(start), "state", r.String())
+		if cancel != nil {
+			cancel(ErrTickWatchdog)
+			r.Cancel(CancelTimeout)
+		}
+	})
+	return ctx, func() {
+		timer.Stop()
+		if cancel != nil {
+			cancel(nil)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package reactor
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/tetratelabs/wazero/api"
+)
+
+// YieldReason is why the guest returned from go_tick, as reported by its
+// optional go_tick_reason export.
+type YieldReason int32
+
+const (
+	// YieldUnknown means the guest gave no reason, or does not export
+	// go_tick_reason.
+	YieldUnknown YieldReason = 0
+	// YieldBudget means the tick budget ran out with goroutines still
+	// runnable; see Config.GoroutinesPerTick.
+	YieldBudget YieldReason = 1
+	// YieldHostIO means every goroutine is blocked on host I/O, such as a
+	// read from stdin or a host function.
+	YieldHostIO YieldReason = 2
+	// YieldChannel means every goroutine is blocked on channels, locks, or
+	// other synchronization within the guest.
+	YieldChannel YieldReason = 3
+	// YieldTimer means the guest is only waiting on timers.
+	YieldTimer YieldReason = 4
+)
+
+// ExtendedResult is the result of LoopOnceEx.
+type ExtendedResult struct {
+	// Result is the classic go_tick result.
+	Result LoopResult
+	// Reason is why the guest yielded, or YieldUnknown if it does not say.
+	Reason YieldReason
+}
+
+// LoopOnceEx runs one iteration of the Go scheduler like LoopOnce, and also
+// reports why the guest yielded, to help schedulers decide when to tick it
+// next.
+//
+// The guest reports the reason through an optional export called right after
+// go_tick:
+//
+//	go_tick_reason() i32 // a YieldReason for the last go_tick
+//
+// If the guest does not export it, Reason is YieldUnknown and Result alone
+// carries the classic idle, ready, or timer state. Reason codes the host does
+// not know are passed through unchanged.
+func (r *Reactor) LoopOnceEx(ctx context.Context) (ExtendedResult, error) {
+	result, err := r.LoopOnce(ctx)
+	if err != nil {
+		return ExtendedResult{Result: result}, err
+	}
+	ext := ExtendedResult{Result: result}
+	fn := r.mod.ExportedFunction("go_tick_reason")
+	if fn == nil {
+		return ext, nil
+	}
+	results, err := r.call(ctx, fn)
+	if err != nil {
+		return ext, fmt.Errorf("go_tick_reason: %w", err)
+	}
+	ext.Reason = YieldReason(api.DecodeI32(results[0]))
+	return ext, nil
+}